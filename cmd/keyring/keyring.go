@@ -0,0 +1,135 @@
+// Команда администратора для управления связкой ключей подписи (config: logic.key_ring.path).
+//
+//	keyring -file ./local_files/keyring.json list
+//	keyring -file ./local_files/keyring.json rotate -kind hmac
+//	keyring -file ./local_files/keyring.json rotate -kind jwt [-private-key ./jwt.pem]
+//	keyring -file ./local_files/keyring.json retire -kind jwt -id jwt-20240131-1a2b3c
+//
+// Серверы перечитывают файл сами (logic.key_ring.reload_interval), перезапуск не нужен.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lesienchik/vk__test/pkg/hashes"
+)
+
+// Срок, после которого ключ, переставший подписывать, гасится при следующей ротации.
+// Должен быть не меньше времени жизни самых долгих токенов и кодов (refresh-токен, восстановление аккаунта).
+const defaultMaxAge = 31 * 24 * time.Hour
+
+func main() {
+	path := flag.String("file", "./local_files/keyring.json", "путь к файлу связки ключей")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("command is required: list, rotate or retire")
+	}
+
+	var err error
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "list":
+		err = list(*path)
+	case "rotate":
+		err = rotate(*path, args)
+	case "retire":
+		err = retire(*path, args)
+	default:
+		err = fmt.Errorf("unknown command %s", command)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func list(path string) error {
+	file, err := hashes.ReadKeyRingFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range []string{hashes.KeyKindHmac, hashes.KeyKindJwt} {
+		entries := file.Hmac
+		if kind == hashes.KeyKindJwt {
+			entries = file.Jwt
+		}
+		for _, entry := range entries {
+			fmt.Printf("%-5s %-24s %-12s created %s\n", kind, entry.Id, entry.State, entry.CreatedAt.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func rotate(path string, args []string) error {
+	flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+	kind := flags.String("kind", hashes.KeyKindHmac, "вид ключа: hmac или jwt")
+	privateKey := flags.String("private-key", "", "jwt: PEM закрытого ключа (по умолчанию генерируется Ed25519)")
+	maxAge := flags.Duration("max-age", defaultMaxAge, "через сколько после ротации старый ключ гасится")
+	flags.Parse(args)
+
+	file, err := readOrCreate(path)
+	if err != nil {
+		return err
+	}
+
+	var entry *hashes.KeyRingEntry
+	switch {
+	case *kind == hashes.KeyKindHmac:
+		entry, err = hashes.GenHmacKeyEntry()
+	case *kind == hashes.KeyKindJwt && *privateKey == "":
+		entry, err = hashes.GenJwtKeyEntry(filepath.Dir(path))
+	case *kind == hashes.KeyKindJwt:
+		var id string
+		id, err = hashes.GenKeyId(hashes.KeyKindJwt)
+		entry = &hashes.KeyRingEntry{Id: id, PrivateKey: *privateKey}
+	default:
+		err = fmt.Errorf("unknown key kind %s", *kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := file.Rotate(*kind, entry, *maxAge); err != nil {
+		return err
+	}
+	if err := hashes.WriteKeyRingFile(path, file); err != nil {
+		return err
+	}
+	fmt.Printf("new %s key %s added\n", *kind, entry.Id)
+	return nil
+}
+
+func retire(path string, args []string) error {
+	flags := flag.NewFlagSet("retire", flag.ExitOnError)
+	kind := flags.String("kind", hashes.KeyKindHmac, "вид ключа: hmac или jwt")
+	id := flags.String("id", "", "id ключа")
+	flags.Parse(args)
+
+	file, err := hashes.ReadKeyRingFile(path)
+	if err != nil {
+		return err
+	}
+	if err := file.Retire(*kind, *id); err != nil {
+		return err
+	}
+	if err := hashes.WriteKeyRingFile(path, file); err != nil {
+		return err
+	}
+	fmt.Printf("%s key %s retired\n", *kind, *id)
+	return nil
+}
+
+func readOrCreate(path string) (*hashes.KeyRingFile, error) {
+	file, err := hashes.ReadKeyRingFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return new(hashes.KeyRingFile), nil
+	}
+	return file, err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Фоновая задача, которая выполняется с заданным интервалом.
+type job struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Запускает задачи по расписанию (каждую в своей горутине, первый запуск - сразу).
+// Возвращает функцию остановки, которая дожидается завершения выполняющихся задач.
+func startJobs(logger *log.Logger, jobs ...job) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+
+			for {
+				if err := j.run(); err != nil {
+					logger.Errorf("job %s: %s", j.name, err)
+				}
+
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+			}
+		}(j)
+	}
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -10,11 +10,14 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/lesienchik/vk__test/internal/api"
+	"github.com/lesienchik/vk__test/internal/audit"
 	"github.com/lesienchik/vk__test/internal/config"
 	"github.com/lesienchik/vk__test/internal/logic"
 	"github.com/lesienchik/vk__test/internal/storage"
 	postgres "github.com/lesienchik/vk__test/pkg/db"
 	"github.com/lesienchik/vk__test/pkg/email"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+	"github.com/lesienchik/vk__test/pkg/ratelimit"
 )
 
 // @title Vktest application
@@ -58,10 +61,36 @@ func main() {
 
 	logger.Info("connection to DB successfully")
 
-	storage := storage.New(logger, db)
+	var limiter ratelimit.Limiter = ratelimit.NewMemory()
+	if cfg.Api.RateLimit.Store == "postgres" {
+		limiter = ratelimit.NewPostgres(db)
+	}
+
+	hasher, err := hashes.NewPasswordHasher(&cfg.Logic.Password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyRing, err := hashes.NewKeyRing(&cfg.Logic.KeyRing, &cfg.Logic.Jwt, cfg.Logic.SecretKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storage := storage.New(logger, db, &cfg.Logic.Lockout)
 	email := email.New(&cfg.Email)
-	logic := logic.New(cfg.Logic.SecretKey, logger, email, storage)
-	api := api.New(cfg, logger, logic)
+	auditor := audit.New(logger, storage.Audit)
+	logic := logic.New(&cfg.Logic, logger, email, storage, hasher, keyRing, auditor)
+	api := api.New(cfg, logger, logic, limiter)
+
+	jobs := []job{
+		{name: "purge_deleted_users", interval: logic.PurgeDeletedUsersInterval(), run: logic.PurgeDeletedUsers},
+		{name: "purge_consumed_tokens", interval: logic.PurgeConsumedTokensInterval(), run: logic.PurgeConsumedTokens},
+	}
+	if cfg.Logic.KeyRing.Path != "" {
+		jobs = append(jobs, job{name: "reload_key_ring", interval: keyRing.ReloadInterval(), run: keyRing.Reload})
+	}
+	stopJobs := startJobs(logger, jobs...)
+	logger.Info("background jobs successfully started")
 
 	termChan, errChan := make(chan os.Signal, 1), make(chan error, 1)
 	signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
@@ -80,5 +109,6 @@ func main() {
 	case <-termChan:
 		logger.Info("vktest service has been successfully stopped")
 		api.Shutdown()
+		stopJobs()
 	}
 }
@@ -15,9 +15,35 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/user/auth": {
-            "post": {
-                "description": "Аутентифицирует пользователя с помощью логина и пароля. Выдает ему access/refresh пару токенов",
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Открытые ключи (JWK Set, RFC 7517) для проверки подписи access-токенов другими сервисами. Ответ не оборачивается в общий формат",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "jwks",
+                "operationId": "jwks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/hashes.Jwks"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает журнал событий безопасности всех пользователей (от новых к старым). Требуется право audit:read",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,26 +51,65 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
                 ],
-                "summary": "userAuth",
-                "operationId": "userAuth",
+                "summary": "adminAuditEvents",
+                "operationId": "adminAuditEvents",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UserAuthReq"
-                        }
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип события",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Только события с id меньше заданного (next_before из предыдущей страницы)",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.RespSucc"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.AuditEventsResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "default": {
@@ -56,9 +121,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/user/confirm/registration": {
+        "/api/v1/admin/users": {
             "get": {
-                "description": "Завершает регистрацию пользователя и выдает access/refresh пару токенов",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает страницу пользователей (по возрастанию id) с поиском и фильтрами. Требуется право users:read",
                 "consumes": [
                     "application/json"
                 ],
@@ -66,24 +136,83 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
                 ],
-                "summary": "userConfirm",
-                "operationId": "userConfirm",
+                "summary": "adminUsers",
+                "operationId": "adminUsers",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Код подтверждения (с почты)",
-                        "name": "code",
-                        "in": "query",
-                        "required": true
+                        "description": "Подстрока псевдонима или почты",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "active",
+                            "suspended",
+                            "banned"
+                        ],
+                        "type": "string",
+                        "description": "Состояние аккаунта",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Роль",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true - только удаленные, false - только неудаленные",
+                        "name": "deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 20, не больше 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.RespSucc"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.AdminUsersResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "default": {
@@ -95,14 +224,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/user/refresh": {
+        "/api/v1/admin/users/{id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Выдает новый access токен пользователю с помощью refresh токена",
+                "description": "Возвращает пользователя с ролями, состоянием 2FA и количеством активных сессий. Требуется право users:read",
                 "consumes": [
                     "application/json"
                 ],
@@ -110,10 +239,85 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
+                ],
+                "summary": "adminUser",
+                "operationId": "adminUser",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.AdminUserDetailResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Удаляет аккаунт пользователя и завершает все его сессии. Окончательно аккаунт удаляется по истечении срока хранения. Требуется право users:write",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "adminDeleteUser",
+                "operationId": "adminDeleteUser",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "userRefresh",
-                "operationId": "userRefresh",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -130,9 +334,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/user/register": {
+        "/api/v1/admin/users/{id}/ban": {
             "post": {
-                "description": "Валидирует и отправляет на почту пользователя ссылку для завершения регистрации",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Блокирует пользователя и завершает все его сессии. Требуется право users:write",
                 "consumes": [
                     "application/json"
                 ],
@@ -140,18 +349,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
                 ],
-                "summary": "userRegister",
-                "operationId": "userRegister",
+                "summary": "adminBanUser",
+                "operationId": "adminBanUser",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина блокировки",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserRegReq"
+                            "$ref": "#/definitions/models.AdminUserActionReq"
                         }
                     }
                 ],
@@ -171,9 +387,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/status": {
-            "get": {
-                "description": "Показывает статус запуска приложения (сервера).",
+        "/api/v1/admin/users/{id}/password/reset": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Делает текущий пароль недействительным, завершает все сессии пользователя и отправляет ему на почту ссылку для сброса пароля. Требуется право users:write",
                 "consumes": [
                     "application/json"
                 ],
@@ -181,66 +402,1740 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Liveness"
+                    "Admin"
+                ],
+                "summary": "adminResetPassword",
+                "operationId": "adminResetPassword",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "status",
-                "operationId": "status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.RespSucc"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.RespErr": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "description": "HTTP-код ошибки",
-                    "type": "integer"
-                },
-                "message": {
-                    "description": "Сообщение об ошибке",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.RespErrMsg"
+        },
+        "/api/v1/admin/users/{id}/sessions": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Завершает все сессии пользователя. Требуется право users:write",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "adminRevokeSessions",
+                "operationId": "adminRevokeSessions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
                         }
-                    ]
-                },
-                "status": {
-                    "description": "Статус ответа = \"error\"",
-                    "type": "string"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
                 }
             }
         },
-        "models.RespErrMsg": {
-            "type": "object",
-            "properties": {
-                "client": {
-                    "description": "Ошибка для клиента (на русском языке)",
-                    "type": "string"
-                },
-                "detail": {
-                    "description": "Ошибка для разработчика",
-                    "type": "string"
+        "/api/v1/admin/users/{id}/suspend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Временно блокирует пользователя и завершает все его сессии. Требуется право users:write",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "adminSuspendUser",
+                "operationId": "adminSuspendUser",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина блокировки",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AdminUserActionReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
                 }
             }
         },
-        "models.RespSucc": {
-            "type": "object",
-            "properties": {
-                "body": {
-                    "description": "Тело ответа",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.RespSuccData"
-                        }
-                    ]
+        "/api/v1/admin/users/{id}/unban": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Снимает с пользователя блокировку. Требуется право users:write",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "adminUnbanUser",
+                "operationId": "adminUnbanUser",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/2fa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Подтверждает подключение 2FA кодом из приложения. Возвращает одноразовые коды восстановления (показываются один раз)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userConfirm2fa",
+                "operationId": "userConfirm2fa",
+                "parameters": [
+                    {
+                        "description": "TOTP-код",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User2faCodeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.User2faRecoveryCodesResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отключает 2FA. Требует пароль и TOTP-код (или код восстановления)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userDisable2fa",
+                "operationId": "userDisable2fa",
+                "parameters": [
+                    {
+                        "description": "Пароль и код",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User2faDisableReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Начинает подключение 2FA: возвращает TOTP-секрет и otpauth:// ссылку для приложения-аутентификатора",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userEnroll2fa",
+                "operationId": "userEnroll2fa",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.User2faEnrollResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/auth": {
+            "post": {
+                "description": "Аутентифицирует пользователя с помощью логина и пароля. Выдает ему access/refresh пару токенов, а при включенной 2FA - challenge токен для /api/v1/user/auth/2fa",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userAuth",
+                "operationId": "userAuth",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserAuthReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/auth/2fa": {
+            "post": {
+                "description": "Второй шаг аутентификации: обменивает challenge токен и TOTP-код (или код восстановления) на access/refresh пару токенов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userAuth2fa",
+                "operationId": "userAuth2fa",
+                "parameters": [
+                    {
+                        "description": "Challenge токен и код",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User2faAuthReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/auth/magic": {
+            "post": {
+                "description": "Отправляет на почту пользователя одноразовую ссылку для входа без пароля (действительна 5 минут)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userMagicLink",
+                "operationId": "userMagicLink",
+                "parameters": [
+                    {
+                        "description": "Почта пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserMagicLinkReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/auth/magic/confirm": {
+            "post": {
+                "description": "Обменивает код из ссылки для входа на access/refresh пару токенов, а при включенной 2FA - на challenge токен для /api/v1/user/auth/2fa. Код действует один раз",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userMagicLinkConfirm",
+                "operationId": "userMagicLinkConfirm",
+                "parameters": [
+                    {
+                        "description": "Код из ссылки (с почты)",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserMagicLinkConfirmReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/confirm/email": {
+            "get": {
+                "description": "Подтверждает новый адрес почты по ссылке из письма и меняет почту пользователя. Все сессии пользователя при этом завершаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userConfirmChangeEmail",
+                "operationId": "userConfirmChangeEmail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Код подтверждения (с новой почты)",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/confirm/email/cancel": {
+            "get": {
+                "description": "Отменяет смену почты по ссылке из уведомления, отправленного на текущий адрес",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userCancelChangeEmail",
+                "operationId": "userCancelChangeEmail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Код отмены (с текущей почты)",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/confirm/registration": {
+            "get": {
+                "description": "Завершает регистрацию пользователя и выдает access/refresh пару токенов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userConfirm",
+                "operationId": "userConfirm",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Код подтверждения (с почты)",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/confirm/restore": {
+            "get": {
+                "description": "Отменяет удаление аккаунта по ссылке из письма (пока не истек срок хранения)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userRestore",
+                "operationId": "userRestore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Код восстановления (с почты)",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/confirm/revoke-sessions": {
+            "post": {
+                "description": "Завершает все сессии пользователя по одноразовой ссылке \"это был не я\" из уведомления о входе с нового устройства.\nСтраница сайта из письма отправляет код этим запросом только после подтверждения пользователем,\nпоэтому переход по ссылке (в том числе автоматический, почтовым клиентом) сессии не завершает",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userRevokeSessionsByCode",
+                "operationId": "userRevokeSessionsByCode",
+                "parameters": [
+                    {
+                        "description": "Код из уведомления (с почты)",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRevokeSessionsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/logout": {
+            "post": {
+                "description": "Завершает текущую сессию пользователя (по refresh токену из cookie)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userLogout",
+                "operationId": "userLogout",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/logout/all": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Завершает все сессии пользователя на всех устройствах",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userLogoutAll",
+                "operationId": "userLogoutAll",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает профиль текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userProfile",
+                "operationId": "userProfile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.UserProfileResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Удаляет аккаунт текущего пользователя (требуется пароль) и завершает все его сессии. До окончательного удаления аккаунт можно восстановить по ссылке из письма",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userDelete",
+                "operationId": "userDelete",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.UserDeleteResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Изменяет профиль текущего пользователя (меняются только переданные поля). Возвращает обновленный профиль",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userUpdateProfile",
+                "operationId": "userUpdateProfile",
+                "parameters": [
+                    {
+                        "description": "Поля профиля",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserUpdateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.UserProfileResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/me/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает журнал событий безопасности аккаунта пользователя (от новых к старым)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userAuditEvents",
+                "operationId": "userAuditEvents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Тип события",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Только события с id меньше заданного (next_before из предыдущей страницы)",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.AuditEventsResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/me/email": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Начинает смену почты: отправляет ссылку подтверждения на новый адрес и уведомление со ссылкой отмены на текущий",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userChangeEmail",
+                "operationId": "userChangeEmail",
+                "parameters": [
+                    {
+                        "description": "Новая почта и текущий пароль",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserChangeEmailReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает архив всех данных, которые хранятся о текущем пользователе",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userExport",
+                "operationId": "userExport",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "$ref": "#/definitions/models.UserExportResp"
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/password/change": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Меняет пароль пользователя. Все ранее выданные refresh токены становятся недействительными, взамен выдается новая access/refresh пара токенов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userChangePassword",
+                "operationId": "userChangePassword",
+                "parameters": [
+                    {
+                        "description": "Текущий и новый пароли",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserChangePassReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/password/reset": {
+            "post": {
+                "description": "Отправляет на почту пользователя ссылку для сброса пароля. Ответ не зависит от того, зарегистрирована ли почта",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userResetPassword",
+                "operationId": "userResetPassword",
+                "parameters": [
+                    {
+                        "description": "Почта пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserResetPassReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/password/reset/confirm": {
+            "post": {
+                "description": "Устанавливает новый пароль пользователя по одноразовому коду из письма",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userConfirmResetPassword",
+                "operationId": "userConfirmResetPassword",
+                "parameters": [
+                    {
+                        "description": "Код из письма и новый пароль",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserConfirmResetPassReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/refresh": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Выдает новый access токен пользователю с помощью refresh токена из cookie. Refresh токен при этом заменяется новым (ротация). Истекший access токен требуется только в строгом режиме",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userRefresh",
+                "operationId": "userRefresh",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/register": {
+            "post": {
+                "description": "Валидирует и отправляет на почту пользователя ссылку для завершения регистрации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userRegister",
+                "operationId": "userRegister",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRegReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает активные сессии (устройства) пользователя. Текущая сессия помечена флагом current",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userSessions",
+                "operationId": "userSessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.RespSucc"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "body": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.RespSuccData"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "data": {
+                                                            "type": "array",
+                                                            "items": {
+                                                                "$ref": "#/definitions/models.UserSessionResp"
+                                                            }
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Завершает одну из сессий пользователя (например, на потерянном устройстве)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "userRevokeSession",
+                "operationId": "userRevokeSession",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/status": {
+            "get": {
+                "description": "Показывает статус запуска приложения (сервера).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Liveness"
+                ],
+                "summary": "status",
+                "operationId": "status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RespSucc"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "hashes.Jwk": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "EC, OKP",
+                    "type": "string"
+                },
+                "e": {
+                    "description": "RSA",
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "description": "EC, OKP",
+                    "type": "string"
+                },
+                "y": {
+                    "description": "EC",
+                    "type": "string"
+                }
+            }
+        },
+        "hashes.Jwks": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/hashes.Jwk"
+                    }
+                }
+            }
+        },
+        "models.AdminUserActionReq": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "Причина (обязательна для блокировки)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AdminUserDetailResp": {
+            "type": "object",
+            "properties": {
+                "active_sessions": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_changed_at": {
+                    "type": "string"
+                },
+                "status_reason": {
+                    "type": "string"
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AdminUserResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_changed_at": {
+                    "type": "string"
+                },
+                "status_reason": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AdminUsersResp": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Всего пользователей, подходящих под фильтр",
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AdminUserResp"
+                    }
+                }
+            }
+        },
+        "models.AuditEventResp": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "description": "Кто совершил действие",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Чей аккаунт затронут",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditEventsResp": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEventResp"
+                    }
+                },
+                "next_before": {
+                    "description": "Значение before для следующей страницы (нет - страница последняя)",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RespErr": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "HTTP-код ошибки",
+                    "type": "integer"
+                },
+                "message": {
+                    "description": "Сообщение об ошибке",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.RespErrMsg"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "Статус ответа = \"error\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.RespErrMsg": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "description": "Ошибка для клиента (на русском языке)",
+                    "type": "string"
+                },
+                "detail": {
+                    "description": "Ошибка для разработчика",
+                    "type": "string"
+                }
+            }
+        },
+        "models.RespSucc": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Тело ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.RespSuccData"
+                        }
+                    ]
                 },
                 "code": {
                     "description": "HTTP-код",
@@ -258,6 +2153,61 @@ const docTemplate = `{
                 "data": {}
             }
         },
+        "models.User2faAuthReq": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "description": "TOTP-код или код восстановления",
+                    "type": "string"
+                }
+            }
+        },
+        "models.User2faCodeReq": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.User2faDisableReq": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "TOTP-код или код восстановления",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.User2faEnrollResp": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "uri": {
+                    "description": "otpauth:// ссылка (для QR-кода)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.User2faRecoveryCodesResp": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "models.UserAuthReq": {
             "type": "object",
             "properties": {
@@ -269,6 +2219,194 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UserChangeEmailReq": {
+            "type": "object",
+            "properties": {
+                "new_email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserChangePassReq": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserConfirmResetPassReq": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserDeleteReq": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserDeleteResp": {
+            "type": "object",
+            "properties": {
+                "purge_at": {
+                    "description": "До этого момента аккаунт можно восстановить по ссылке из письма",
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserExportDeviceResp": {
+            "type": "object",
+            "properties": {
+                "first_seen_at": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserExportLockoutResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "locked_until": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserExportResp": {
+            "type": "object",
+            "properties": {
+                "audit_events": {
+                    "description": "Журнал событий безопасности аккаунта",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEventResp"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "known_devices": {
+                    "description": "Устройства, с которых пользователь входил",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserExportDeviceResp"
+                    }
+                },
+                "login_lockouts": {
+                    "description": "Блокировки входа после неудачных попыток",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserExportLockoutResp"
+                    }
+                },
+                "profile": {
+                    "$ref": "#/definitions/models.UserProfileResp"
+                },
+                "sessions": {
+                    "description": "Все сессии, включая завершенные (история входов)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserExportSessionResp"
+                    }
+                }
+            }
+        },
+        "models.UserExportSessionResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserMagicLinkConfirmReq": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserMagicLinkReq": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserProfileResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
         "models.UserRegReq": {
             "type": "object",
             "properties": {
@@ -282,18 +2420,76 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.UserResetPassReq": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserRevokeSessionsReq": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserSessionResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Сессия, с которой пришел запрос",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserUpdateReq": {
+            "type": "object",
+            "properties": {
+                "username": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
-	Host:             "",
-	BasePath:         "",
+	Version:          "2.0",
+	Host:             "localhost:9100",
+	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "Vktest application",
+	Description:      "The backend service for the site vktest.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
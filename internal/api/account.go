@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userDelete
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Удаляет аккаунт текущего пользователя (требуется пароль) и завершает все его сессии. До окончательного удаления аккаунт можно восстановить по ссылке из письма
+// @ID userDelete
+// @Accept json
+// @Produce json
+// @Param input body models.UserDeleteReq true "Текущий пароль"
+// @Success 202 {object} models.RespSucc{body=models.RespSuccData{data=models.UserDeleteResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/me [delete]
+func (a *Api) userDelete(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserDeleteReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	userReq.Id = userId
+
+	resp, errs := a.logic.UserDelete(&userReq)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.userDelRefreshCookie(ctx)
+	a.respSucc(ctx, fasthttp.StatusAccepted, resp)
+}
+
+// @Summary userRestore
+// @Tags User
+// @Description Отменяет удаление аккаунта по ссылке из письма (пока не истек срок хранения)
+// @ID userRestore
+// @Accept json
+// @Produce json
+// @Param code query string true "Код восстановления (с почты)"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/confirm/restore [get]
+func (a *Api) userRestore(ctx *fasthttp.RequestCtx) {
+	code := ctx.QueryArgs().Peek("code")
+	if len(code) == 0 {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("empty restore code"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserRestore(string(code)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, "account restored")
+}
+
+// @Summary userExport
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Возвращает архив всех данных, которые хранятся о текущем пользователе
+// @ID userExport
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.UserExportResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/me/export [get]
+func (a *Api) userExport(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	resp, errs := a.logic.UserExport(userId)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	ctx.Response.Header.Set("Content-Disposition", `attachment; filename="vktest-export.json"`)
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
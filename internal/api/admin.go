@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary adminUsers
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Возвращает страницу пользователей (по возрастанию id) с поиском и фильтрами. Требуется право users:read
+// @ID adminUsers
+// @Accept json
+// @Produce json
+// @Param query query string false "Подстрока псевдонима или почты"
+// @Param status query string false "Состояние аккаунта" Enums(pending, active, suspended, banned)
+// @Param role query string false "Роль"
+// @Param deleted query bool false "true - только удаленные, false - только неудаленные"
+// @Param limit query int false "Размер страницы (по умолчанию 20, не больше 100)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.AdminUsersResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users [get]
+func (a *Api) adminUsers(ctx *fasthttp.RequestCtx) {
+	args := ctx.QueryArgs()
+	filter := &m.UserFilter{
+		Query:  string(args.Peek("query")),
+		Status: string(args.Peek("status")),
+		Role:   string(args.Peek("role")),
+	}
+
+	var err error
+	if args.Has("deleted") {
+		deleted := args.GetBool("deleted")
+		filter.Deleted = &deleted
+	}
+	if args.Has("limit") {
+		filter.Limit, err = strconv.Atoi(string(args.Peek("limit")))
+	}
+	if err == nil && args.Has("offset") {
+		filter.Offset, err = strconv.Atoi(string(args.Peek("offset")))
+	}
+	if err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	resp, errs := a.logic.AdminUsers(filter)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// @Summary adminUser
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Возвращает пользователя с ролями, состоянием 2FA и количеством активных сессий. Требуется право users:read
+// @ID adminUser
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.AdminUserDetailResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id} [get]
+func (a *Api) adminUser(ctx *fasthttp.RequestCtx) {
+	adminReq, errs := a.adminActionReq(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	resp, errs := a.logic.AdminUser(adminReq.UserId)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// @Summary adminSuspendUser
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Временно блокирует пользователя и завершает все его сессии. Требуется право users:write
+// @ID adminSuspendUser
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Param input body models.AdminUserActionReq true "Причина блокировки"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id}/suspend [post]
+func (a *Api) adminSuspendUser(ctx *fasthttp.RequestCtx) {
+	a.adminAction(ctx, a.logic.AdminSuspendUser, "user suspended")
+}
+
+// @Summary adminBanUser
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Блокирует пользователя и завершает все его сессии. Требуется право users:write
+// @ID adminBanUser
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Param input body models.AdminUserActionReq true "Причина блокировки"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id}/ban [post]
+func (a *Api) adminBanUser(ctx *fasthttp.RequestCtx) {
+	a.adminAction(ctx, a.logic.AdminBanUser, "user banned")
+}
+
+// @Summary adminUnbanUser
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Снимает с пользователя блокировку. Требуется право users:write
+// @ID adminUnbanUser
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id}/unban [post]
+func (a *Api) adminUnbanUser(ctx *fasthttp.RequestCtx) {
+	a.adminAction(ctx, a.logic.AdminUnbanUser, "user unbanned")
+}
+
+// @Summary adminResetPassword
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Делает текущий пароль недействительным, завершает все сессии пользователя и отправляет ему на почту ссылку для сброса пароля. Требуется право users:write
+// @ID adminResetPassword
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id}/password/reset [post]
+func (a *Api) adminResetPassword(ctx *fasthttp.RequestCtx) {
+	a.adminAction(ctx, a.logic.AdminResetPassword, "password reset link sent")
+}
+
+// @Summary adminRevokeSessions
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Завершает все сессии пользователя. Требуется право users:write
+// @ID adminRevokeSessions
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id}/sessions [delete]
+func (a *Api) adminRevokeSessions(ctx *fasthttp.RequestCtx) {
+	a.adminAction(ctx, a.logic.AdminRevokeSessions, "sessions revoked")
+}
+
+// @Summary adminDeleteUser
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Удаляет аккаунт пользователя и завершает все его сессии. Окончательно аккаунт удаляется по истечении срока хранения. Требуется право users:write
+// @ID adminDeleteUser
+// @Accept json
+// @Produce json
+// @Param id path int true "Id пользователя"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/users/{id} [delete]
+func (a *Api) adminDeleteUser(ctx *fasthttp.RequestCtx) {
+	a.adminAction(ctx, a.logic.AdminDeleteUser, "user deleted")
+}
+
+// Выполняет действие администратора над пользователем из пути запроса и отвечает сообщением msg.
+func (a *Api) adminAction(ctx *fasthttp.RequestCtx, action func(*m.AdminUserActionReq, *m.ClientInfo) *m.Err, msg string) {
+	adminReq, errs := a.adminActionReq(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	if errs := action(adminReq, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, msg)
+}
+
+// Собирает запрос действия администратора: id администратора (из токена), id пользователя (из пути)
+// и необязательное тело запроса.
+func (a *Api) adminActionReq(ctx *fasthttp.RequestCtx) (*m.AdminUserActionReq, *m.Err) {
+	adminReq := new(m.AdminUserActionReq)
+	if body := ctx.PostBody(); len(body) > 0 {
+		if err := json.Unmarshal(body, adminReq); err != nil {
+			return nil, &m.Err{
+				Code:  fasthttp.StatusBadRequest,
+				Error: err,
+			}
+		}
+	}
+
+	adminId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		return nil, errs
+	}
+	adminReq.AdminId = adminId
+
+	rawId, _ := ctx.UserValue("id").(string)
+	userId, err := strconv.Atoi(rawId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("invalid user id"),
+		}
+	}
+	adminReq.UserId = userId
+	return adminReq, nil
+}
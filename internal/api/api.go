@@ -1,9 +1,6 @@
 package api
 
 import (
-	"log"
-	"runtime/debug"
-	"strings"
 	"time"
 
 	fasthttprouter "github.com/fasthttp/router"
@@ -14,17 +11,22 @@ import (
 	_ "github.com/lesienchik/vk__test/docs"
 	"github.com/lesienchik/vk__test/internal/config"
 	"github.com/lesienchik/vk__test/internal/logic"
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/ratelimit"
 )
 
 type Api struct {
-	addr   string
-	logger *logrus.Logger
-	router *fasthttprouter.Router
-	server *fasthttp.Server
-	logic  *logic.Logic
+	addr         string
+	allowOrigins []string
+	logger       *logrus.Logger
+	router       *fasthttprouter.Router
+	server       *fasthttp.Server
+	logic        *logic.Logic
+	limiter      ratelimit.Limiter
+	ratePolicies map[string]*ratePolicy
 }
 
-func New(cfg *config.Config, logger *logrus.Logger, logic *logic.Logic) *Api {
+func New(cfg *config.Config, logger *logrus.Logger, logic *logic.Logic, limiter ratelimit.Limiter) *Api {
 	api := new(Api)
 	router := fasthttprouter.New()
 	httpServer := &fasthttp.Server{
@@ -32,57 +34,91 @@ func New(cfg *config.Config, logger *logrus.Logger, logic *logic.Logic) *Api {
 		MaxRequestBodySize: 1_000_000,
 		ReadTimeout:        time.Duration(cfg.Api.ReadTimeout) * time.Second,
 		WriteTimeout:       time.Duration(cfg.Api.WriteTimeout) * time.Second,
-		Handler:            httpHandle(api),
 	}
 
 	api.addr = cfg.Api.Addr
+	api.allowOrigins = cfg.Api.AllowOrigins
 	api.logger = logger
 	api.router = router
 	api.server = httpServer
 	api.logic = logic
+	api.limiter = limiter
+	api.ratePolicies = newRatePolicies(&cfg.Api.RateLimit)
 
+	api.routes()
+
+	// Общая цепочка мидлвар для всех запросов (первая в списке - внешняя).
+	httpServer.Handler = chain(router.Handler,
+		api.middlRecover,
+		api.middlRequestId,
+		api.middlLogger,
+		api.middlCors,
+	)
 	return api
 }
 
-func httpHandle(api *Api) fasthttp.RequestHandler {
-	return func(ctx *fasthttp.RequestCtx) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[panic]: Panic during api operation: %s\n%s\n", r, string(debug.Stack()))
-			}
-		}()
-		api.handle(ctx)
-	}
-}
+// Регистрирует маршруты. Мидлвары конкретного маршрута перечисляются после обработчика.
+func (a *Api) routes() {
+	a.router.NotFound = a.notFound
+	a.router.MethodNotAllowed = a.methodNotAllowed
+	a.router.GlobalOPTIONS = a.options
 
-func (a *Api) handle(ctx *fasthttp.RequestCtx) {
-	path, method := string(ctx.Path()), string(ctx.Method())
-	switch {
-	case ctx.IsOptions():
-		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
-		ctx.Response.Header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		ctx.Response.Header.Set("Access-Control-Allow-Headers", "Content-Type")
-		ctx.SetStatusCode(fasthttp.StatusNoContent) // 204 No Content
+	// Мидлвары для маршрутов, доступных только аутентифицированному пользователю.
+	auth := []middleware{a.middlVerify, a.middlRateLimit(ratePolicyUser)}
 
 	// User
-	case path == "/api/v1/user/register" && method == fasthttp.MethodPost:
-		a.userRegister(ctx)
-	case path == "/api/v1/user/confirm/registration" && method == fasthttp.MethodGet:
-		a.userConfirm(ctx)
-	case path == "/api/v1/user/auth" && method == fasthttp.MethodPost:
-		a.userAuth(ctx)
-	case path == "/api/v1/user/refresh" && method == fasthttp.MethodGet:
-		a.userRefresh(ctx)
+	user := a.router.Group("/api/v1/user")
+	user.POST("/register", chain(a.userRegister, a.middlRateLimit(ratePolicyRegisterIp, ratePolicyRegisterEmail)))
+	user.GET("/confirm/registration", chain(a.userConfirm, a.middlRateLimit(ratePolicyConfirmIp)))
+	user.POST("/auth", chain(a.userAuth, a.middlRateLimit(ratePolicyAuthIp)))
+	user.POST("/auth/2fa", chain(a.userAuth2fa, a.middlRateLimit(ratePolicyAuthIp)))
+	user.POST("/auth/magic", chain(a.userMagicLink, a.middlRateLimit(ratePolicyMagicLinkIp, ratePolicyMagicLinkEmail)))
+	user.POST("/auth/magic/confirm", chain(a.userMagicLinkConfirm, a.middlRateLimit(ratePolicyAuthIp)))
+	user.GET("/refresh", chain(a.userRefresh, a.middlRateLimit(ratePolicyRefreshIp)))
+	user.POST("/logout", chain(a.userLogout, a.middlRateLimit(ratePolicyRefreshIp)))
+	user.GET("/me", chain(a.userProfile, auth...))
+	user.PATCH("/me", chain(a.userUpdateProfile, auth...))
+	user.DELETE("/me", chain(a.userDelete, auth...))
+	user.GET("/me/export", chain(a.userExport, auth...))
+	user.GET("/me/audit", chain(a.userAuditEvents, auth...))
+	user.POST("/me/email", chain(a.userChangeEmail, a.middlVerify, a.middlRateLimit(ratePolicyUser, ratePolicyChangeEmail)))
+	user.GET("/confirm/email", chain(a.userConfirmChangeEmail, a.middlRateLimit(ratePolicyConfirmIp)))
+	user.GET("/confirm/email/cancel", chain(a.userCancelChangeEmail, a.middlRateLimit(ratePolicyConfirmIp)))
+	user.GET("/confirm/restore", chain(a.userRestore, a.middlRateLimit(ratePolicyConfirmIp)))
+	user.POST("/logout/all", chain(a.userLogoutAll, auth...))
+	user.POST("/confirm/revoke-sessions", chain(a.userRevokeSessionsByCode, a.middlRateLimit(ratePolicyConfirmIp)))
+	user.GET("/sessions", chain(a.userSessions, auth...))
+	user.DELETE("/sessions/{id}", chain(a.userRevokeSession, auth...))
+	user.POST("/2fa/enroll", chain(a.userEnroll2fa, auth...))
+	user.POST("/2fa/confirm", chain(a.userConfirm2fa, auth...))
+	user.POST("/2fa/disable", chain(a.userDisable2fa, auth...))
+	user.POST("/password/change", chain(a.userChangePassword, auth...))
+	user.POST("/password/reset", chain(a.userResetPassword, a.middlRateLimit(ratePolicyPassResetIp, ratePolicyPassResetEmail)))
+	user.POST("/password/reset/confirm", chain(a.userConfirmResetPassword, a.middlRateLimit(ratePolicyConfirmIp)))
+
+	// Admin
+	usersRead := []middleware{a.middlRequirePermission(m.PermUsersRead), a.middlRateLimit(ratePolicyUser)}
+	usersWrite := []middleware{a.middlRequirePermission(m.PermUsersWrite), a.middlRateLimit(ratePolicyUser)}
+
+	admin := a.router.Group("/api/v1/admin")
+	admin.GET("/users", chain(a.adminUsers, usersRead...))
+	admin.GET("/users/{id}", chain(a.adminUser, usersRead...))
+	admin.DELETE("/users/{id}", chain(a.adminDeleteUser, usersWrite...))
+	admin.POST("/users/{id}/suspend", chain(a.adminSuspendUser, usersWrite...))
+	admin.POST("/users/{id}/ban", chain(a.adminBanUser, usersWrite...))
+	admin.POST("/users/{id}/unban", chain(a.adminUnbanUser, usersWrite...))
+	admin.POST("/users/{id}/password/reset", chain(a.adminResetPassword, usersWrite...))
+	admin.DELETE("/users/{id}/sessions", chain(a.adminRevokeSessions, usersWrite...))
+	admin.GET("/audit", chain(a.adminAuditEvents, a.middlRequirePermission(m.PermAuditRead), a.middlRateLimit(ratePolicyUser)))
+
+	// Открытые ключи jwt
+	a.router.GET("/.well-known/jwks.json", a.jwks)
+
 	// Swagger docs
-	case strings.HasPrefix(path, "/swagger"):
-		fasthttpswagger.WrapHandler(fasthttpswagger.InstanceName("swagger"))(ctx)
+	a.router.GET("/swagger/{filepath:*}", fasthttpswagger.WrapHandler(fasthttpswagger.InstanceName("swagger")))
 
 	// Test api
-	case path == "/status" && method == fasthttp.MethodGet:
-		a.status(ctx)
-	default:
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
-	}
+	a.router.GET("/status", a.status)
 }
 
 func (a *Api) Start() error {
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userAuditEvents
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Возвращает журнал событий безопасности аккаунта пользователя (от новых к старым)
+// @ID userAuditEvents
+// @Accept json
+// @Produce json
+// @Param type query string false "Тип события"
+// @Param before query int false "Только события с id меньше заданного (next_before из предыдущей страницы)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.AuditEventsResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/me/audit [get]
+func (a *Api) userAuditEvents(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	filter, errs := a.auditFilter(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	resp, errs := a.logic.UserAuditEvents(userId, filter)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// @Summary adminAuditEvents
+// @Security ApiKeyAuth
+// @Tags Admin
+// @Description Возвращает журнал событий безопасности всех пользователей (от новых к старым). Требуется право audit:read
+// @ID adminAuditEvents
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Id пользователя"
+// @Param type query string false "Тип события"
+// @Param before query int false "Только события с id меньше заданного (next_before из предыдущей страницы)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.AuditEventsResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/admin/audit [get]
+func (a *Api) adminAuditEvents(ctx *fasthttp.RequestCtx) {
+	filter, errs := a.auditFilter(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	if args := ctx.QueryArgs(); args.Has("user_id") {
+		userId, err := strconv.Atoi(string(args.Peek("user_id")))
+		if err != nil {
+			a.respErrs(ctx, &m.Err{
+				Code:  fasthttp.StatusBadRequest,
+				Error: err,
+			})
+			return
+		}
+		filter.UserId = userId
+	}
+
+	resp, errs := a.logic.AdminAuditEvents(filter)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// Собирает общие для журналов параметры выборки из строки запроса.
+func (a *Api) auditFilter(ctx *fasthttp.RequestCtx) (*m.AuditFilter, *m.Err) {
+	args := ctx.QueryArgs()
+	filter := &m.AuditFilter{Type: string(args.Peek("type"))}
+
+	var err error
+	if args.Has("before") {
+		filter.Before, err = strconv.ParseInt(string(args.Peek("before")), 10, 64)
+	}
+	if err == nil && args.Has("limit") {
+		filter.Limit, err = strconv.Atoi(string(args.Peek("limit")))
+	}
+	if err != nil {
+		return nil, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		}
+	}
+	return filter, nil
+}
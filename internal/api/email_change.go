@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userChangeEmail
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Начинает смену почты: отправляет ссылку подтверждения на новый адрес и уведомление со ссылкой отмены на текущий
+// @ID userChangeEmail
+// @Accept json
+// @Produce json
+// @Param input body models.UserChangeEmailReq true "Новая почта и текущий пароль"
+// @Success 202 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/me/email [post]
+func (a *Api) userChangeEmail(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserChangeEmailReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	userReq.Id = userId
+
+	if errs := a.logic.UserChangeEmail(&userReq); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusAccepted, "confirmation sent")
+}
+
+// @Summary userConfirmChangeEmail
+// @Tags User
+// @Description Подтверждает новый адрес почты по ссылке из письма и меняет почту пользователя. Все сессии пользователя при этом завершаются
+// @ID userConfirmChangeEmail
+// @Accept json
+// @Produce json
+// @Param code query string true "Код подтверждения (с новой почты)"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/confirm/email [get]
+func (a *Api) userConfirmChangeEmail(ctx *fasthttp.RequestCtx) {
+	code := ctx.QueryArgs().Peek("code")
+	if len(code) == 0 {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("empty confirmation code"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserConfirmChangeEmail(string(code)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, "email changed")
+}
+
+// @Summary userCancelChangeEmail
+// @Tags User
+// @Description Отменяет смену почты по ссылке из уведомления, отправленного на текущий адрес
+// @ID userCancelChangeEmail
+// @Accept json
+// @Produce json
+// @Param code query string true "Код отмены (с текущей почты)"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/confirm/email/cancel [get]
+func (a *Api) userCancelChangeEmail(ctx *fasthttp.RequestCtx) {
+	code := ctx.QueryArgs().Peek("code")
+	if len(code) == 0 {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("empty cancel code"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserCancelChangeEmail(string(code)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, "email change canceled")
+}
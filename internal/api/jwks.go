@@ -0,0 +1,27 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+)
+
+// @Summary jwks
+// @Tags Keys
+// @Description Открытые ключи (JWK Set, RFC 7517) для проверки подписи access-токенов другими сервисами. Ответ не оборачивается в общий формат
+// @ID jwks
+// @Produce json
+// @Success 200 {object} hashes.Jwks
+// @Router /.well-known/jwks.json [get]
+func (a *Api) jwks(ctx *fasthttp.RequestCtx) {
+	data, err := json.Marshal(a.logic.Jwks())
+	if err != nil {
+		a.logger.Errorf("api.jwks(1): %s", err)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.Response.Header.Set("Cache-Control", "public, max-age=300")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.Response.SetBodyRaw(data)
+}
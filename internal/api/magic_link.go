@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userMagicLink
+// @Tags User
+// @Description Отправляет на почту пользователя одноразовую ссылку для входа без пароля (действительна 5 минут)
+// @ID userMagicLink
+// @Accept json
+// @Produce json
+// @Param input body models.UserMagicLinkReq true "Почта пользователя"
+// @Success 202 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/auth/magic [post]
+func (a *Api) userMagicLink(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserMagicLinkReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	if errs := a.logic.UserMagicLink(&userReq, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusAccepted, "request accepted")
+}
+
+// @Summary userMagicLinkConfirm
+// @Tags User
+// @Description Обменивает код из ссылки для входа на access/refresh пару токенов, а при включенной 2FA - на challenge токен для /api/v1/user/auth/2fa. Код действует один раз
+// @ID userMagicLinkConfirm
+// @Accept json
+// @Produce json
+// @Param input body models.UserMagicLinkConfirmReq true "Код из ссылки (с почты)"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/auth/magic/confirm [post]
+func (a *Api) userMagicLinkConfirm(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserMagicLinkConfirmReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, challenge, errs := a.logic.UserMagicLinkConfirm(&userReq, a.clientInfo(ctx))
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	// Включена 2FA: токены выдаются только после ввода кода (userAuth2fa).
+	if challenge != "" {
+		a.respSucc(ctx, fasthttp.StatusOK, m.User2faChallengeResp{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		})
+		return
+	}
+	a.userSetJwtTokens(ctx, userId)
+}
@@ -2,49 +2,224 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 
 	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
 )
 
-// Мидлвара, которая каждый раз проверяет авторизацию пользователя, с помощью access токена.
-func (a *Api) middlVerify(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+const (
+	requestIdHeader = "X-Request-ID"
+	requestIdSize   = 16
+	corsMethods     = "GET, POST, PATCH, DELETE, OPTIONS"
+	corsHeaders     = "Content-Type, Authorization, X-Request-ID"
+	corsExpose      = "X-Request-ID, Retry-After"
+)
+
+// Мидлвара оборачивает обработчик и возвращает новый.
+type middleware func(next fasthttp.RequestHandler) fasthttp.RequestHandler
+
+// Собирает цепочку мидлвар вокруг обработчика: первая в списке выполняется первой.
+func chain(handler fasthttp.RequestHandler, mws ...middleware) fasthttp.RequestHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
+
+// Мидлвара, которая перехватывает панику в обработчике и отвечает 500 вместо обрыва соединения.
+func (a *Api) middlRecover(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
-		authHeader := ctx.Request.Header.Peek("Authorization")
-		if len(authHeader) == 0 {
-			a.respErrs(ctx, &m.Err{
-				Code:  fasthttp.StatusUnauthorized,
-				Error: errors.New("empty authorization header"),
-			})
-			return
+		defer func() {
+			if r := recover(); r != nil {
+				a.logger.WithField("request_id", ctx.UserValue("requestId")).
+					Errorf("[panic]: Panic during api operation: %v\n%s", r, debug.Stack())
+				ctx.ResetBody()
+				a.respErrs(ctx, &m.Err{
+					Code:      fasthttp.StatusInternalServerError,
+					ClientMsg: m.MsgInternalServerError,
+					Error:     fmt.Errorf("panic: %v", r),
+				})
+			}
+		}()
+		next(ctx)
+	}
+}
+
+// Мидлвара, которая присваивает запросу идентификатор (берет из заголовка X-Request-ID или генерирует)
+// и возвращает его клиенту.
+func (a *Api) middlRequestId(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		requestId := string(ctx.Request.Header.Peek(requestIdHeader))
+		if requestId == "" || len(requestId) > 128 {
+			token, err := hashes.GenRandomToken(requestIdSize)
+			if err != nil {
+				a.logger.Errorf("api.middlRequestId(1): %s", err)
+			}
+			requestId = token
+		}
+		ctx.SetUserValue("requestId", requestId)
+		ctx.Response.Header.Set(requestIdHeader, requestId)
+		next(ctx)
+	}
+}
+
+// Мидлвара, которая пишет в лог каждый запрос: метод, путь, код ответа и время обработки.
+func (a *Api) middlLogger(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next(ctx)
+		a.logger.WithFields(logrus.Fields{
+			"method":     string(ctx.Method()),
+			"path":       string(ctx.Path()),
+			"status":     ctx.Response.StatusCode(),
+			"duration":   time.Since(start).String(),
+			"ip":         ctx.RemoteIP().String(),
+			"request_id": ctx.UserValue("requestId"),
+		}).Info("api request")
+	}
+}
+
+// Мидлвара, которая выставляет CORS-заголовки по списку api.allow_origins из конфига
+// (пустой список или "*" - любой источник) и отвечает на preflight-запросы.
+func (a *Api) middlCors(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		origin := string(ctx.Request.Header.Peek("Origin"))
+		switch {
+		case len(a.allowOrigins) == 0 || slices.Contains(a.allowOrigins, "*"):
+			ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && slices.Contains(a.allowOrigins, origin):
+			ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+			ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+			ctx.Response.Header.Add("Vary", "Origin")
 		}
+		ctx.Response.Header.Set("Access-Control-Expose-Headers", corsExpose)
 
-		authParts := strings.Split(string(authHeader), " ")
-		if len(authParts) != 2 {
-			a.respErrs(ctx, &m.Err{
-				Code:  fasthttp.StatusUnauthorized,
-				Error: errors.New("authorization header does not consist of 2 parts"),
-			})
+		if ctx.IsOptions() && len(ctx.Request.Header.Peek("Access-Control-Request-Method")) > 0 {
+			ctx.Response.Header.Set("Access-Control-Allow-Methods", corsMethods)
+			ctx.Response.Header.Set("Access-Control-Allow-Headers", corsHeaders)
+			ctx.SetStatusCode(fasthttp.StatusNoContent) // 204 No Content
 			return
 		}
+		next(ctx)
+	}
+}
 
-		if authParts[0] != "Bearer" {
-			a.respErrs(ctx, &m.Err{
-				Code:  fasthttp.StatusUnauthorized,
-				Error: errors.New("authorization header does not start with Bearer"),
-			})
+// Мидлвара, которая каждый раз проверяет авторизацию пользователя, с помощью access токена.
+func (a *Api) middlVerify(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		access, errs := a.bearerToken(ctx)
+		if errs != nil {
+			a.respErrs(ctx, errs)
 			return
 		}
 
-		access := authParts[1]
-		userId, errs := a.logic.UserVerify(access)
+		claims, errs := a.logic.UserVerify(access)
 		if errs != nil {
 			a.respErrs(ctx, errs)
 			return
 		}
-		ctx.SetUserValue("userId", userId)
+		ctx.SetUserValue("userId", claims.Id)
+		ctx.SetUserValue("sessionId", claims.SessionId)
+		ctx.SetUserValue("claims", claims)
 		next(ctx)
 	}
 }
+
+// Мидлвара, которая пропускает только пользователей с правом permission. Сама проверяет access-токен
+// (middlVerify), поэтому используется вместо нее. Права берутся из access-токена.
+func (a *Api) middlRequirePermission(permission string) middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return a.middlVerify(func(ctx *fasthttp.RequestCtx) {
+			claims, ok := ctx.UserValue("claims").(*m.UserAuthClaims)
+			if !ok || !claims.HasPermission(permission) {
+				a.respErrs(ctx, &m.Err{
+					Code:      fasthttp.StatusForbidden,
+					ClientMsg: "Недостаточно прав",
+					Error:     fmt.Errorf("permission %s required", permission),
+				})
+				return
+			}
+			next(ctx)
+		})
+	}
+}
+
+// Достает id пользователя, сохраненный мидлварой middlVerify.
+func (a *Api) ctxUserId(ctx *fasthttp.RequestCtx) (int, *m.Err) {
+	userId, ok := ctx.UserValue("userId").(int)
+	if !ok {
+		return -1, &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("user id not found in request context"),
+		}
+	}
+	return userId, nil
+}
+
+// Собирает сведения о клиенте (user agent и ip), от которого пришел запрос, и id запроса.
+func (a *Api) clientInfo(ctx *fasthttp.RequestCtx) *m.ClientInfo {
+	requestId, _ := ctx.UserValue("requestId").(string)
+	return &m.ClientInfo{
+		UserAgent: string(ctx.UserAgent()),
+		Ip:        ctx.RemoteIP().String(),
+		RequestId: requestId,
+	}
+}
+
+// Достает access-токен из заголовка Authorization вида "Bearer <token>".
+func (a *Api) bearerToken(ctx *fasthttp.RequestCtx) (string, *m.Err) {
+	authHeader := ctx.Request.Header.Peek("Authorization")
+	if len(authHeader) == 0 {
+		return "", &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("empty authorization header"),
+		}
+	}
+
+	authParts := strings.Split(string(authHeader), " ")
+	if len(authParts) != 2 {
+		return "", &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("authorization header does not consist of 2 parts"),
+		}
+	}
+
+	if authParts[0] != "Bearer" {
+		return "", &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("authorization header does not start with Bearer"),
+		}
+	}
+	return authParts[1], nil
+}
+
+// Ответ на запрос к несуществующему маршруту.
+func (a *Api) notFound(ctx *fasthttp.RequestCtx) {
+	a.respErrs(ctx, &m.Err{
+		Code:      fasthttp.StatusNotFound,
+		ClientMsg: "Страница не найдена",
+		Error:     errors.New("route not found: " + string(ctx.Path())),
+	})
+}
+
+// Ответ на запрос с неподдерживаемым методом (заголовок Allow выставляет роутер).
+func (a *Api) methodNotAllowed(ctx *fasthttp.RequestCtx) {
+	a.respErrs(ctx, &m.Err{
+		Code:      fasthttp.StatusMethodNotAllowed,
+		ClientMsg: "Метод не поддерживается",
+		Error:     errors.New("method " + string(ctx.Method()) + " not allowed"),
+	})
+}
+
+// Ответ на OPTIONS без CORS preflight (заголовок Allow выставляет роутер).
+func (a *Api) options(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(fasthttp.StatusNoContent) // 204 No Content
+}
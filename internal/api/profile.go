@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userProfile
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Возвращает профиль текущего пользователя
+// @ID userProfile
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.UserProfileResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/me [get]
+func (a *Api) userProfile(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	resp, errs := a.logic.UserProfile(userId)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// @Summary userUpdateProfile
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Изменяет профиль текущего пользователя (меняются только переданные поля). Возвращает обновленный профиль
+// @ID userUpdateProfile
+// @Accept json
+// @Produce json
+// @Param input body models.UserUpdateReq true "Поля профиля"
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.UserProfileResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/me [patch]
+func (a *Api) userUpdateProfile(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserUpdateReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	userReq.Id = userId
+
+	resp, errs := a.logic.UserUpdateProfile(&userReq)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
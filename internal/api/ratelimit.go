@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/lesienchik/vk__test/internal/config"
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// Имена политик ограничения частоты запросов (их же можно переопределить в конфиге api.rate_limit.policies).
+const (
+	ratePolicyRegisterIp     = "register_ip"
+	ratePolicyRegisterEmail  = "register_email"
+	ratePolicyConfirmIp      = "confirm_ip"
+	ratePolicyAuthIp         = "auth_ip"
+	ratePolicyRefreshIp      = "refresh_ip"
+	ratePolicyPassResetIp    = "password_reset_ip"
+	ratePolicyPassResetEmail = "password_reset_email"
+	ratePolicyMagicLinkIp    = "magic_link_ip"
+	ratePolicyMagicLinkEmail = "magic_link_email"
+	ratePolicyChangeEmail    = "change_email"
+	ratePolicyUser           = "user"
+)
+
+// Политика ограничения: не больше limit запросов за window на один ключ.
+type ratePolicy struct {
+	limit  int
+	window time.Duration
+	key    func(ctx *fasthttp.RequestCtx) string // Пустой ключ - политика к запросу не применяется
+}
+
+// Собирает политики по умолчанию и применяет переопределения из конфига.
+func newRatePolicies(cfg *config.RateLimit) map[string]*ratePolicy {
+	policies := map[string]*ratePolicy{
+		// Регистрация, сброс пароля и вход по ссылке отправляют письма: ограничиваем и по ip, и по адресу получателя.
+		// Смена почты доступна только после входа, поэтому ограничивается по пользователю.
+		ratePolicyRegisterIp:     {limit: 10, window: time.Hour, key: rateKeyIp},
+		ratePolicyRegisterEmail:  {limit: 3, window: time.Hour, key: rateKeyEmail},
+		ratePolicyPassResetIp:    {limit: 10, window: time.Hour, key: rateKeyIp},
+		ratePolicyPassResetEmail: {limit: 3, window: time.Hour, key: rateKeyEmail},
+		ratePolicyMagicLinkIp:    {limit: 10, window: time.Hour, key: rateKeyIp},
+		ratePolicyMagicLinkEmail: {limit: 3, window: time.Hour, key: rateKeyEmail},
+		ratePolicyChangeEmail:    {limit: 3, window: time.Hour, key: rateKeyUser},
+		ratePolicyConfirmIp:      {limit: 30, window: time.Minute, key: rateKeyIp},
+		ratePolicyAuthIp:         {limit: 30, window: time.Minute, key: rateKeyIp},
+		ratePolicyRefreshIp:      {limit: 60, window: time.Minute, key: rateKeyIp},
+		ratePolicyUser:           {limit: 120, window: time.Minute, key: rateKeyUser},
+	}
+
+	for name, override := range cfg.Policies {
+		policy, ok := policies[name]
+		if !ok {
+			continue
+		}
+		if override.Limit > 0 {
+			policy.limit = override.Limit
+		}
+		if override.Window > 0 {
+			policy.window = time.Duration(override.Window) * time.Second
+		}
+	}
+	return policies
+}
+
+// Мидлвара, которая ограничивает частоту запросов по перечисленным политикам.
+// Для политик по пользователю должна стоять после middlVerify.
+func (a *Api) middlRateLimit(policies ...string) middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			for _, name := range policies {
+				policy, ok := a.ratePolicies[name]
+				if !ok {
+					a.logger.Errorf("api.middlRateLimit: unknown rate limit policy %s", name)
+					continue
+				}
+
+				key := policy.key(ctx)
+				if key == "" {
+					continue
+				}
+
+				allowed, retryAfter, err := a.limiter.Allow(name+":"+key, policy.limit, policy.window)
+				if err != nil {
+					// Недоступность хранилища счетчиков не должна останавливать сервис.
+					a.logger.Errorf("api.middlRateLimit: %s", err)
+					continue
+				}
+				if !allowed {
+					a.respErrs(ctx, &m.Err{
+						Code:       fasthttp.StatusTooManyRequests,
+						ClientMsg:  "Слишком много запросов. Попробуйте позже",
+						Error:      errors.New("rate limit exceeded: " + name),
+						RetryAfter: max(1, int(math.Ceil(retryAfter.Seconds()))),
+					})
+					return
+				}
+			}
+			next(ctx)
+		}
+	}
+}
+
+// Ключ по ip клиента.
+func rateKeyIp(ctx *fasthttp.RequestCtx) string {
+	return ctx.RemoteIP().String()
+}
+
+// Ключ по id пользователя (выставляется мидлварой middlVerify).
+func rateKeyUser(ctx *fasthttp.RequestCtx) string {
+	userId, ok := ctx.UserValue("userId").(int)
+	if !ok {
+		return ""
+	}
+	return strconv.Itoa(userId)
+}
+
+// Ключ по адресу получателя письма (поле email в теле запроса).
+func rateKeyEmail(ctx *fasthttp.RequestCtx) string {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(ctx.PostBody(), &body); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/valyala/fasthttp"
 
@@ -54,6 +55,9 @@ func (a *Api) respErrs(ctx *fasthttp.RequestCtx, errs *m.Err) {
 		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
 		return
 	}
+	if errs.RetryAfter > 0 {
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(errs.RetryAfter))
+	}
 	ctx.SetStatusCode(resp.Code)
 	ctx.Response.SetBodyRaw(data)
 }
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userLogout
+// @Tags User
+// @Description Завершает текущую сессию пользователя (по refresh токену из cookie)
+// @ID userLogout
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/logout [post]
+func (a *Api) userLogout(ctx *fasthttp.RequestCtx) {
+	refresh := ctx.Request.Header.Cookie("refresh_token")
+	if len(refresh) == 0 {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("refresh token not found"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserLogout(string(refresh), a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.userDelRefreshCookie(ctx)
+	a.respSucc(ctx, fasthttp.StatusOK, "logged out")
+}
+
+// @Summary userLogoutAll
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Завершает все сессии пользователя на всех устройствах
+// @ID userLogoutAll
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/logout/all [post]
+func (a *Api) userLogoutAll(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	if errs := a.logic.UserLogoutAll(userId, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.userDelRefreshCookie(ctx)
+	a.respSucc(ctx, fasthttp.StatusOK, "logged out")
+}
+
+// @Summary userRevokeSessionsByCode
+// @Tags User
+// @Description Завершает все сессии пользователя по одноразовой ссылке "это был не я" из уведомления о входе с нового устройства.
+// @Description Страница сайта из письма отправляет код этим запросом только после подтверждения пользователем,
+// @Description поэтому переход по ссылке (в том числе автоматический, почтовым клиентом) сессии не завершает
+// @ID userRevokeSessionsByCode
+// @Accept json
+// @Produce json
+// @Param input body models.UserRevokeSessionsReq true "Код из уведомления (с почты)"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/confirm/revoke-sessions [post]
+func (a *Api) userRevokeSessionsByCode(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserRevokeSessionsReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+	if userReq.Code == "" {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("empty revoke code"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserRevokeSessionsByCode(&userReq, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, "sessions revoked")
+}
+
+// @Summary userSessions
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Возвращает активные сессии (устройства) пользователя. Текущая сессия помечена флагом current
+// @ID userSessions
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=[]models.UserSessionResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/sessions [get]
+func (a *Api) userSessions(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	sessionId, _ := ctx.UserValue("sessionId").(int)
+
+	sessions, errs := a.logic.UserSessions(userId, sessionId)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, sessions)
+}
+
+// @Summary userRevokeSession
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Завершает одну из сессий пользователя (например, на потерянном устройстве)
+// @ID userRevokeSession
+// @Accept json
+// @Produce json
+// @Param id path int true "Id сессии"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/sessions/{id} [delete]
+func (a *Api) userRevokeSession(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	rawId, _ := ctx.UserValue("id").(string)
+	sessionId, err := strconv.Atoi(rawId)
+	if err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("invalid session id"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserRevokeSession(userId, sessionId, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, "session revoked")
+}
+
+// Удаляет cookie с refresh-токеном на стороне клиента.
+func (a *Api) userDelRefreshCookie(ctx *fasthttp.RequestCtx) {
+	refreshCookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(refreshCookie)
+
+	refreshCookie.SetKey("refresh_token")
+	refreshCookie.SetPath("/")
+	refreshCookie.SetHTTPOnly(true)
+	refreshCookie.SetExpire(fasthttp.CookieExpireDelete)
+	ctx.Response.Header.SetCookie(refreshCookie)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// @Summary userAuth2fa
+// @Tags User
+// @Description Второй шаг аутентификации: обменивает challenge токен и TOTP-код (или код восстановления) на access/refresh пару токенов
+// @ID userAuth2fa
+// @Accept json
+// @Produce json
+// @Param input body models.User2faAuthReq true "Challenge токен и код"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/auth/2fa [post]
+func (a *Api) userAuth2fa(ctx *fasthttp.RequestCtx) {
+	var userReq m.User2faAuthReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, errs := a.logic.UserAuth2fa(&userReq, a.clientInfo(ctx))
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.userSetJwtTokens(ctx, userId)
+}
+
+// @Summary userEnroll2fa
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Начинает подключение 2FA: возвращает TOTP-секрет и otpauth:// ссылку для приложения-аутентификатора
+// @ID userEnroll2fa
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.User2faEnrollResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/2fa/enroll [post]
+func (a *Api) userEnroll2fa(ctx *fasthttp.RequestCtx) {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	resp, errs := a.logic.UserEnroll2fa(userId)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// @Summary userConfirm2fa
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Подтверждает подключение 2FA кодом из приложения. Возвращает одноразовые коды восстановления (показываются один раз)
+// @ID userConfirm2fa
+// @Accept json
+// @Produce json
+// @Param input body models.User2faCodeReq true "TOTP-код"
+// @Success 200 {object} models.RespSucc{body=models.RespSuccData{data=models.User2faRecoveryCodesResp}}
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/2fa/confirm [post]
+func (a *Api) userConfirm2fa(ctx *fasthttp.RequestCtx) {
+	var userReq m.User2faCodeReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	resp, errs := a.logic.UserConfirm2fa(userId, userReq.Code)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, resp)
+}
+
+// @Summary userDisable2fa
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Отключает 2FA. Требует пароль и TOTP-код (или код восстановления)
+// @ID userDisable2fa
+// @Accept json
+// @Produce json
+// @Param input body models.User2faDisableReq true "Пароль и код"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/2fa/disable [post]
+func (a *Api) userDisable2fa(ctx *fasthttp.RequestCtx) {
+	var userReq m.User2faDisableReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	userReq.Id = userId
+
+	if errs := a.logic.UserDisable2fa(&userReq); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusOK, "2fa disabled")
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -31,7 +30,7 @@ func (a *Api) userRegister(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	errs := a.logic.UserRegister(&userReq)
+	errs := a.logic.UserRegister(&userReq, a.clientInfo(ctx))
 	if errs != nil {
 		a.respErrs(ctx, errs)
 		return
@@ -59,7 +58,7 @@ func (a *Api) userConfirm(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	userId, errs := a.logic.UserConfirm(string(code))
+	userId, errs := a.logic.UserConfirm(string(code), a.clientInfo(ctx))
 	if errs != nil {
 		a.respErrs(ctx, errs)
 		return
@@ -69,7 +68,7 @@ func (a *Api) userConfirm(ctx *fasthttp.RequestCtx) {
 
 // Устанавливает refresh-токен в cookie и возвращает в ответе access-токен.
 func (a *Api) userSetJwtTokens(ctx *fasthttp.RequestCtx, userId int) {
-	tokens, errs := a.logic.UserSetJwtTokens(userId)
+	tokens, errs := a.logic.UserSetJwtTokens(userId, a.clientInfo(ctx))
 	if errs != nil {
 		a.respErrs(ctx, errs)
 		return
@@ -84,7 +83,14 @@ func (a *Api) userSetJwtTokens(ctx *fasthttp.RequestCtx, userId int) {
 	}
 
 	access, refresh := tokens[0], tokens[1]
+	a.userSetRefreshCookie(ctx, refresh)
 
+	data := m.UserAccessResp{Token: access}
+	a.respSucc(ctx, fasthttp.StatusOK, data)
+}
+
+// Устанавливает refresh-токен в cookie.
+func (a *Api) userSetRefreshCookie(ctx *fasthttp.RequestCtx, refresh string) {
 	refreshCookie := fasthttp.AcquireCookie()
 	defer fasthttp.ReleaseCookie(refreshCookie)
 
@@ -94,14 +100,11 @@ func (a *Api) userSetJwtTokens(ctx *fasthttp.RequestCtx, userId int) {
 	refreshCookie.SetHTTPOnly(true)
 	refreshCookie.SetExpire(time.Now().Add(30 * 24 * time.Hour))
 	ctx.Response.Header.SetCookie(refreshCookie)
-
-	data := m.UserAccessResp{Token: access}
-	a.respSucc(ctx, fasthttp.StatusOK, data)
 }
 
 // @Summary userAuth
 // @Tags User
-// @Description Аутентифицирует пользователя с помощью логина и пароля. Выдает ему access/refresh пару токенов
+// @Description Аутентифицирует пользователя с помощью логина и пароля. Выдает ему access/refresh пару токенов, а при включенной 2FA - challenge токен для /api/v1/user/auth/2fa
 // @ID userAuth
 // @Accept json
 // @Produce json
@@ -119,18 +122,27 @@ func (a *Api) userAuth(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	userId, errs := a.logic.UserAuth(&userReq)
+	userId, challenge, errs := a.logic.UserAuth(&userReq, a.clientInfo(ctx))
 	if errs != nil {
 		a.respErrs(ctx, errs)
 		return
 	}
+
+	// Включена 2FA: токены выдаются только после ввода кода (userAuth2fa).
+	if challenge != "" {
+		a.respSucc(ctx, fasthttp.StatusOK, m.User2faChallengeResp{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		})
+		return
+	}
 	a.userSetJwtTokens(ctx, userId)
 }
 
 // @Summary userRefresh
 // @Security ApiKeyAuth
 // @Tags User
-// @Description Выдает новый access токен пользователю с помощью refresh токена
+// @Description Выдает новый access токен пользователю с помощью refresh токена из cookie. Refresh токен при этом заменяется новым (ротация). Истекший access токен требуется только в строгом режиме
 // @ID userRefresh
 // @Accept json
 // @Produce json
@@ -138,46 +150,136 @@ func (a *Api) userAuth(ctx *fasthttp.RequestCtx) {
 // @Failure default {object} models.RespErr
 // @Router /api/v1/user/refresh [get]
 func (a *Api) userRefresh(ctx *fasthttp.RequestCtx) {
-	authHeader := ctx.Request.Header.Peek("Authorization")
-	if len(authHeader) == 0 {
+	// Access-токен необязателен: он нужен только в строгом режиме (strict_refresh).
+	var access string
+	if len(ctx.Request.Header.Peek("Authorization")) != 0 {
+		var errs *m.Err
+		if access, errs = a.bearerToken(ctx); errs != nil {
+			a.respErrs(ctx, errs)
+			return
+		}
+	}
+
+	refresh := ctx.Request.Header.Cookie("refresh_token")
+	if len(refresh) == 0 {
 		a.respErrs(ctx, &m.Err{
 			Code:  fasthttp.StatusUnauthorized,
-			Error: errors.New("empty authorization header"),
+			Error: errors.New("refresh token not found"),
 		})
 		return
 	}
 
-	authParts := strings.Split(string(authHeader), " ")
-	if len(authParts) != 2 {
+	tokens, errs := a.logic.UserRefresh(access, string(refresh), a.clientInfo(ctx))
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+
+	if len(tokens) != 2 {
 		a.respErrs(ctx, &m.Err{
-			Code:  fasthttp.StatusUnauthorized,
-			Error: errors.New("authorization header does not consist of 2 parts"),
+			Code:  fasthttp.StatusInternalServerError,
+			Error: errors.New("error creating a token pair"),
 		})
 		return
 	}
 
-	if authParts[0] != "Bearer" {
+	// Refresh-токен одноразовый: клиент получает новый вместе с access-токеном.
+	a.userSetRefreshCookie(ctx, tokens[1])
+	a.respSucc(ctx, fasthttp.StatusOK, m.UserAccessResp{Token: tokens[0]})
+}
+
+// @Summary userChangePassword
+// @Security ApiKeyAuth
+// @Tags User
+// @Description Меняет пароль пользователя. Все ранее выданные refresh токены становятся недействительными, взамен выдается новая access/refresh пара токенов
+// @ID userChangePassword
+// @Accept json
+// @Produce json
+// @Param input body models.UserChangePassReq true "Текущий и новый пароли"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/password/change [post]
+func (a *Api) userChangePassword(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserChangePassReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
 		a.respErrs(ctx, &m.Err{
-			Code:  fasthttp.StatusUnauthorized,
-			Error: errors.New("authorization header does not start with Bearer"),
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
 		})
 		return
 	}
 
-	access := authParts[1]
-	refresh := ctx.Request.Header.Cookie("refresh_token")
-	if len(refresh) == 0 {
+	userId, errs := a.ctxUserId(ctx)
+	if errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	userReq.Id = userId
+
+	if errs := a.logic.UserChangePassword(&userReq, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.userSetJwtTokens(ctx, userId)
+}
+
+// @Summary userResetPassword
+// @Tags User
+// @Description Отправляет на почту пользователя ссылку для сброса пароля. Ответ не зависит от того, зарегистрирована ли почта
+// @ID userResetPassword
+// @Accept json
+// @Produce json
+// @Param input body models.UserResetPassReq true "Почта пользователя"
+// @Success 202 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/password/reset [post]
+func (a *Api) userResetPassword(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserResetPassReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
 		a.respErrs(ctx, &m.Err{
-			Code:  fasthttp.StatusUnauthorized,
-			Error: errors.New("refresh token not found"),
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
 		})
 		return
 	}
 
-	token, errs := a.logic.UserRefresh(access, string(refresh))
-	if errs != nil {
+	if errs := a.logic.UserResetPassword(&userReq, a.clientInfo(ctx)); errs != nil {
+		a.respErrs(ctx, errs)
+		return
+	}
+	a.respSucc(ctx, fasthttp.StatusAccepted, "request accepted")
+}
+
+// @Summary userConfirmResetPassword
+// @Tags User
+// @Description Устанавливает новый пароль пользователя по одноразовому коду из письма
+// @ID userConfirmResetPassword
+// @Accept json
+// @Produce json
+// @Param input body models.UserConfirmResetPassReq true "Код из письма и новый пароль"
+// @Success 200 {object} models.RespSucc
+// @Failure default {object} models.RespErr
+// @Router /api/v1/user/password/reset/confirm [post]
+func (a *Api) userConfirmResetPassword(ctx *fasthttp.RequestCtx) {
+	var userReq m.UserConfirmResetPassReq
+	if err := json.Unmarshal(ctx.PostBody(), &userReq); err != nil {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		})
+		return
+	}
+	if userReq.Code == "" {
+		a.respErrs(ctx, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("empty reset code"),
+		})
+		return
+	}
+
+	if errs := a.logic.UserConfirmResetPassword(&userReq, a.clientInfo(ctx)); errs != nil {
 		a.respErrs(ctx, errs)
 		return
 	}
-	a.respSucc(ctx, fasthttp.StatusOK, m.UserAccessResp{Token: token})
+	a.respSucc(ctx, fasthttp.StatusOK, "password changed")
 }
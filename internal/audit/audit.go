@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/internal/storage"
+)
+
+// Журнал событий безопасности. Все события пишутся через него, события хранятся в storage.Audit.
+type Auditor struct {
+	logger *logrus.Logger
+	store  storage.Audit
+}
+
+func New(logger *logrus.Logger, store storage.Audit) *Auditor {
+	return &Auditor{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// Записывает событие. Ошибка записи не прерывает действие пользователя: событие целиком уходит в лог приложения.
+func (a *Auditor) Record(event *m.AuditEvent) {
+	if err := a.store.Create(event); err != nil {
+		a.logger.WithFields(logrus.Fields{
+			"audit_type":       event.Type,
+			"audit_actor_id":   event.ActorId,
+			"audit_user_id":    event.UserId,
+			"audit_ip":         event.Ip,
+			"audit_user_agent": event.UserAgent,
+			"request_id":       event.RequestId,
+			"audit_metadata":   event.Metadata,
+		}).Error(fmt.Errorf("audit.Auditor.Record(1): %w", err))
+	}
+}
+
+// Возвращает страницу событий, подходящих под фильтр (от новых к старым).
+func (a *Auditor) Events(filter *m.AuditFilter) ([]*m.AuditEvent, error) {
+	events, err := a.store.GetPage(filter)
+	if err != nil {
+		return nil, fmt.Errorf("audit.Auditor.Events(1): %w", err)
+	}
+	return events, nil
+}
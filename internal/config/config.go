@@ -9,15 +9,84 @@ type Config struct {
 }
 
 type Api struct {
-	Addr         string   `json:"addr"`
-	ReadTimeout  int      `json:"read_timeout"`
-	WriteTimeout int      `json:"write_timeout"`
-	IdleTimeout  int      `json:"idle_timeout"`
-	AllowOrigins []string `json:"allow_origins"`
+	Addr         string    `json:"addr"`
+	ReadTimeout  int       `json:"read_timeout"`
+	WriteTimeout int       `json:"write_timeout"`
+	IdleTimeout  int       `json:"idle_timeout"`
+	AllowOrigins []string  `json:"allow_origins"`
+	RateLimit    RateLimit `json:"rate_limit"`
+}
+
+type RateLimit struct { // Ограничение частоты запросов.
+	Store    string                `json:"store"`    // Хранилище счетчиков: "memory" (по умолчанию) или "postgres" (общее для всех реплик)
+	Policies map[string]RatePolicy `json:"policies"` // Переопределение политик по имени (см. api/ratelimit.go)
+}
+
+type RatePolicy struct {
+	Limit  int `json:"limit"`  // Запросов за окно
+	Window int `json:"window"` // Окно (сек)
 }
 
 type Logic struct {
-	SecretKey string `env:"SECRET_KEY,notEmpty"`
+	SecretKey     string   `env:"SECRET_KEY,notEmpty"`
+	StrictRefresh bool     `json:"strict_refresh"` // Требовать истекший access-токен при обновлении токенов
+	StatusCache   int      `json:"status_cache"`   // Сколько хранится состояние аккаунта и сессии для проверки access-токенов (сек)
+	Lockout       Lockout  `json:"lockout"`
+	Deletion      Deletion `json:"deletion"`
+	Password      Password `json:"password"`
+	Jwt           Jwt      `json:"jwt"`
+	KeyRing       KeyRing  `json:"key_ring"`
+}
+
+type Lockout struct { // Защита от перебора паролей.
+	Store              string `json:"store"`                // Хранилище счетчиков попыток: "memory" или "postgres" (по умолчанию)
+	MaxAccountFailures int    `json:"max_account_failures"` // Неудачных попыток на аккаунт до блокировки
+	MaxIpFailures      int    `json:"max_ip_failures"`      // Неудачных попыток с одного ip до блокировки
+	Window             int    `json:"window"`               // Окно подсчета неудачных попыток (сек)
+	LockoutTime        int    `json:"lockout_time"`         // Длительность блокировки (сек)
+	BaseDelay          int    `json:"base_delay"`           // Задержка после первой неудачи (мс), удваивается с каждой следующей
+	MaxDelay           int    `json:"max_delay"`            // Максимальная задержка между попытками (мс)
+}
+
+type Deletion struct { // Удаление аккаунтов.
+	GracePeriod   int `json:"grace_period"`   // Сколько аккаунт хранится после удаления и может быть восстановлен (сек)
+	PurgeInterval int `json:"purge_interval"` // Как часто окончательно удаляются аккаунты с истекшим сроком (сек)
+}
+
+type Password struct { // Хэширование паролей. Хэши с другими параметрами пересчитываются при входе.
+	Algorithm         string `json:"algorithm"`          // "argon2id" (по умолчанию) или "bcrypt"
+	BcryptCost        int    `json:"bcrypt_cost"`        // Стоимость bcrypt (по умолчанию 10)
+	Argon2Memory      int    `json:"argon2_memory"`      // Память argon2id (КиБ)
+	Argon2Iterations  int    `json:"argon2_iterations"`  // Число проходов argon2id
+	Argon2Parallelism int    `json:"argon2_parallelism"` // Число потоков argon2id
+
+	// Перец: секрет сервера, с которым пароль смешивается (HMAC) перед хэшированием.
+	// Задается списком "<версия>:<секрет>" через запятую; удаленная из списка версия больше не принимается.
+	Peppers       []string `env:"PASSWORD_PEPPERS" envSeparator:","`
+	PepperVersion int      `json:"pepper_version"` // Версия для новых хэшей (по умолчанию - наибольшая из заданных)
+}
+
+type Jwt struct { // Ключи подписи jwt из конфига загружаются в связку ключей вместе с ключами из ее файла.
+	Keys         []JwtKey `json:"keys"`
+	SigningKey   string   `json:"signing_key"`   // Id ключа для новых токенов (по умолчанию - первый ключ с закрытой частью)
+	Issuer       string   `json:"issuer"`        // Издатель токенов (iss)
+	Audience     string   `json:"audience"`      // Получатель токенов (aud)
+	ClockSkew    int      `json:"clock_skew"`    // Допустимое расхождение часов при проверке exp, nbf и iat (сек)
+	LegacyHmac   bool     `json:"legacy_hmac"`   // Принимать токены HS256, когда в связке есть асимметричные ключи jwt (на время перехода)
+	LegacyClaims bool     `json:"legacy_claims"` // Принимать токены без typ, iss и aud, выпущенные до их появления (на время перехода)
+}
+
+type JwtKey struct { // Алгоритм (RS256, ES256/ES384/ES512, EdDSA) определяется по типу ключа.
+	Id         string `json:"id"`          // Значение kid в заголовке токена
+	PrivateKey string `json:"private_key"` // Путь к PEM-файлу закрытого ключа (PKCS#8, PKCS#1 или SEC 1)
+	PublicKey  string `json:"public_key"`  // Путь к PEM-файлу открытого ключа, если закрытого нет (только проверка)
+}
+
+type KeyRing struct { // Связка ключей подписи jwt и кодов (HmacGenHash). Без файла используются только SECRET_KEY и jwt.keys.
+	Path            string `json:"path"`             // JSON-файл со связкой (меняется командой cmd/keyring)
+	ReloadInterval  int    `json:"reload_interval"`  // Как часто перечитывать файл (сек)
+	ActivationDelay int    `json:"activation_delay"` // Через сколько после добавления ключ начинает подписывать (сек), чтобы его успели загрузить все реплики
+	LegacySecret    string `json:"legacy_secret"`    // Состояние SECRET_KEY, если в файле есть hmac-ключи: "verify_only" (по умолчанию) или "retired"
 }
 
 type Postgres struct {
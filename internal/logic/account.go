@@ -0,0 +1,263 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/lesienchik/vk__test/internal/config"
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+)
+
+// Значения по умолчанию для удаления аккаунтов (если не заданы в конфиге).
+const (
+	defaultDeletionGracePeriod   = 30 * 24 * 60 * 60 // сек
+	defaultDeletionPurgeInterval = 60 * 60           // сек
+)
+
+// Заполняет незаданные параметры удаления аккаунтов значениями по умолчанию.
+func deletionWithDefaults(cfg config.Deletion) config.Deletion {
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = defaultDeletionGracePeriod
+	}
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = defaultDeletionPurgeInterval
+	}
+	return cfg
+}
+
+// Удаляет аккаунт пользователя (после повторного ввода пароля). Аккаунт сразу становится недоступен,
+// а окончательно удаляется по истечении срока хранения. До этого его можно восстановить по ссылке из письма.
+func (l *Logic) UserDelete(userReq *m.UserDeleteReq) (*m.UserDeleteResp, *m.Err) {
+	userDb, exists, err := l.storage.User.GetById(userReq.Id)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	if _, err := l.hasher.Verify(userDb.Password, userReq.Password); err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный текущий пароль",
+			Error:     err,
+		}
+	}
+
+	marked, err := l.storage.User.MarkDeletedById(userDb.Id)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !marked {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Аккаунт уже удален",
+			Error:     errors.New("user already deleted"),
+		}
+	}
+
+	l.statusCache.forget(userDb.Id)
+
+	// Удаленный аккаунт не должен оставаться авторизованным ни на одном устройстве.
+	if err := l.storage.Session.RevokeAllByUserId(userDb.Id); err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	// Время удаления берем из базы: к нему привязан код восстановления.
+	userDb, _, err = l.storage.User.GetById(userDb.Id)
+	if err != nil || userDb.DeletedAt == nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     fmt.Errorf("logic.UserDelete: failed to get deletion time: %w", err),
+		}
+	}
+
+	gracePeriod := time.Duration(l.deletion.GracePeriod) * time.Second
+	purgeAt := userDb.DeletedAt.Add(gracePeriod)
+
+	restoreCode, err := hashes.HmacGenHash(m.UserRestoreCode{
+		Id:        userDb.Id,
+		Purpose:   codePurposeRestore,
+		DeletedAt: userDb.DeletedAt.UnixMicro(),
+	}, gracePeriod, l.keyRing)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	go func() {
+		if err := l.email.SendDeletionNotice(userDb.Email, restoreCode, purgeAt); err != nil {
+			l.logger.Error(fmt.Errorf("logic.UserDelete: %w", err))
+		}
+	}()
+	return &m.UserDeleteResp{PurgeAt: purgeAt}, nil
+}
+
+// Отменяет удаление аккаунта по ссылке из письма (пока не истек срок хранения).
+func (l *Logic) UserRestore(code string) *m.Err {
+	restoreCode := new(m.UserRestoreCode)
+	hashingStatus, err := hashes.HmacParseAndValidateHash(code, restoreCode, l.keyRing)
+	if err != nil {
+		if hashingStatus == hashes.HashExpires {
+			return &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Срок восстановления аккаунта истек",
+				Error:     err,
+			}
+		}
+
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для восстановления аккаунта",
+			Error:     err,
+		}
+	}
+	if restoreCode.Purpose != codePurposeRestore {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для восстановления аккаунта",
+			Error:     errors.New("invalid code purpose"),
+		}
+	}
+
+	restored, err := l.storage.User.RestoreById(restoreCode.Id, time.UnixMicro(restoreCode.DeletedAt))
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !restored {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Аккаунт уже восстановлен или удален окончательно",
+			Error:     errors.New("deleted user not found"),
+		}
+	}
+	l.statusCache.forget(restoreCode.Id)
+	return nil
+}
+
+// Собирает архив всех данных, которые хранятся о пользователе.
+func (l *Logic) UserExport(userId int) (*m.UserExportResp, *m.Err) {
+	profile, errs := l.UserProfile(userId)
+	if errs != nil {
+		return nil, errs
+	}
+
+	sessions, err := l.storage.Session.GetAllByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	lockouts, err := l.storage.LoginLockout.GetByKey(lockoutAccountKey(profile.Email))
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	devices, err := l.storage.KnownDevice.GetAllByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	auditEvents, err := l.auditAllUserEvents(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	resp := &m.UserExportResp{
+		ExportedAt:    time.Now(),
+		Profile:       profile,
+		Sessions:      make([]*m.UserExportSessionResp, 0, len(sessions)),
+		LoginLockouts: make([]*m.UserExportLockoutResp, 0, len(lockouts)),
+		KnownDevices:  make([]*m.UserExportDeviceResp, 0, len(devices)),
+		AuditEvents:   auditEvents,
+	}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &m.UserExportSessionResp{
+			Id:         session.Id,
+			UserAgent:  session.UserAgent,
+			Ip:         session.Ip,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+	for _, device := range devices {
+		resp.KnownDevices = append(resp.KnownDevices, &m.UserExportDeviceResp{
+			UserAgent:   device.UserAgent,
+			Ip:          device.Ip,
+			FirstSeenAt: device.FirstSeenAt,
+			LastSeenAt:  device.LastSeenAt,
+		})
+	}
+	for _, lockout := range lockouts {
+		resp.LoginLockouts = append(resp.LoginLockouts, &m.UserExportLockoutResp{
+			Ip:          lockout.Ip,
+			Failures:    lockout.Failures,
+			LockedUntil: lockout.LockedUntil,
+			CreatedAt:   lockout.CreatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// Окончательно удаляет аккаунты, у которых истек срок хранения после удаления. Запускается по расписанию.
+func (l *Logic) PurgeDeletedUsers() error {
+	before := time.Now().Add(-time.Duration(l.deletion.GracePeriod) * time.Second)
+	purged, err := l.storage.User.DeleteMarkedBefore(before)
+	if err != nil {
+		return fmt.Errorf("logic.PurgeDeletedUsers(1): %w", err)
+	}
+	if purged > 0 {
+		l.logger.Infof("logic.PurgeDeletedUsers: %d deleted accounts purged", purged)
+	}
+	return nil
+}
+
+// Как часто нужно запускать PurgeDeletedUsers.
+func (l *Logic) PurgeDeletedUsersInterval() time.Duration {
+	return time.Duration(l.deletion.PurgeInterval) * time.Second
+}
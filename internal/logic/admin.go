@@ -0,0 +1,342 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+const (
+	adminUsersDefaultLimit = 20  // Размер страницы списка пользователей по умолчанию
+	adminUsersMaxLimit     = 100 // Наибольший размер страницы списка пользователей
+	adminReasonMaxLength   = 500 // Наибольшая длина причины блокировки
+)
+
+// Возвращает страницу пользователей, подходящих под фильтр.
+func (l *Logic) AdminUsers(filter *m.UserFilter) (*m.AdminUsersResp, *m.Err) {
+	if filter.Limit <= 0 {
+		filter.Limit = adminUsersDefaultLimit
+	}
+	if filter.Limit > adminUsersMaxLimit || filter.Offset < 0 {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: fmt.Sprintf("Размер страницы - не больше %d", adminUsersMaxLimit),
+			Error:     fmt.Errorf("invalid page: limit %d, offset %d", filter.Limit, filter.Offset),
+		}
+	}
+	switch filter.Status {
+	case "", m.UserStatusPending, m.UserStatusActive, m.UserStatusSuspended, m.UserStatusBanned:
+	default:
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неизвестное состояние аккаунта",
+			Error:     fmt.Errorf("unknown user status %s", filter.Status),
+		}
+	}
+
+	users, total, err := l.storage.User.GetList(filter)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	resp := &m.AdminUsersResp{
+		Users:  make([]*m.AdminUserResp, 0, len(users)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, adminUserResp(user))
+	}
+	return resp, nil
+}
+
+// Возвращает подробности о пользователе: роли, 2FA и количество активных сессий.
+func (l *Logic) AdminUser(userId int) (*m.AdminUserDetailResp, *m.Err) {
+	userDb, errs := l.adminGetUser(userId)
+	if errs != nil {
+		return nil, errs
+	}
+
+	userRoles, err := l.storage.Role.GetByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	userTotp, totpExists, err := l.storage.Totp.GetByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	sessions, err := l.storage.Session.GetActiveByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	return &m.AdminUserDetailResp{
+		AdminUserResp:    *adminUserResp(userDb),
+		Roles:            userRoles.Roles,
+		TwoFactorEnabled: totpExists && userTotp.Enabled,
+		ActiveSessions:   len(sessions),
+	}, nil
+}
+
+// Временно блокирует пользователя (нужна причина) и завершает все его сессии.
+func (l *Logic) AdminSuspendUser(adminReq *m.AdminUserActionReq, client *m.ClientInfo) *m.Err {
+	return l.adminSetUserStatus(adminReq, client, m.UserStatusSuspended, m.AuditEventAdminSuspend)
+}
+
+// Блокирует пользователя (нужна причина) и завершает все его сессии.
+func (l *Logic) AdminBanUser(adminReq *m.AdminUserActionReq, client *m.ClientInfo) *m.Err {
+	return l.adminSetUserStatus(adminReq, client, m.UserStatusBanned, m.AuditEventAdminBan)
+}
+
+// Снимает с пользователя блокировку.
+func (l *Logic) AdminUnbanUser(adminReq *m.AdminUserActionReq, client *m.ClientInfo) *m.Err {
+	return l.adminSetUserStatus(adminReq, client, m.UserStatusActive, m.AuditEventAdminUnban)
+}
+
+// Переводит аккаунт в состояние status. При блокировке завершает все сессии пользователя.
+func (l *Logic) adminSetUserStatus(adminReq *m.AdminUserActionReq, client *m.ClientInfo, status, eventType string) *m.Err {
+	adminReq.Reason = strings.TrimSpace(adminReq.Reason)
+	if status != m.UserStatusActive {
+		if errs := l.adminCheckNotSelf(adminReq); errs != nil {
+			return errs
+		}
+		if adminReq.Reason == "" || len([]rune(adminReq.Reason)) > adminReasonMaxLength {
+			return &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: fmt.Sprintf("Укажите причину блокировки (до %d символов)", adminReasonMaxLength),
+				Error:     errors.New("invalid status reason"),
+			}
+		}
+	}
+
+	userDb, errs := l.adminGetUser(adminReq.UserId)
+	if errs != nil {
+		return errs
+	}
+	if userDb.DeletedAt != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Аккаунт удален",
+			Error:     errors.New("user deleted"),
+		}
+	}
+	if !userStatusCanTransition(userDb.Status, status) {
+		return &m.Err{
+			Code:      fasthttp.StatusConflict,
+			ClientMsg: "Нельзя перевести аккаунт в это состояние из текущего",
+			Error:     fmt.Errorf("status transition %s -> %s is not allowed", userDb.Status, status),
+		}
+	}
+
+	// Причина блокировки после разблокировки не нужна.
+	reason := adminReq.Reason
+	if status == m.UserStatusActive {
+		reason = ""
+	}
+
+	updated, err := l.storage.User.UpdateStatusById(userDb.Id, userDb.Status, status, reason)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !updated {
+		// Состояние успели изменить параллельно.
+		return &m.Err{
+			Code:      fasthttp.StatusConflict,
+			ClientMsg: "Состояние аккаунта уже изменилось, обновите данные",
+			Error:     errors.New("user status changed concurrently"),
+		}
+	}
+	l.statusCache.forget(userDb.Id)
+
+	if status != m.UserStatusActive {
+		if err := l.storage.Session.RevokeAllByUserId(userDb.Id); err != nil {
+			return &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+	}
+	l.logger.Infof("logic.adminSetUserStatus: admin %d set status %s for user %d", adminReq.AdminId, status, userDb.Id)
+	l.auditRecord(eventType, adminReq.AdminId, userDb.Id, client, map[string]any{"from": userDb.Status, "to": status, "reason": reason})
+	return nil
+}
+
+// Принудительно сбрасывает пароль: делает старый пароль недействительным, завершает все сессии пользователя
+// и отправляет ему ссылку для сброса пароля.
+func (l *Logic) AdminResetPassword(adminReq *m.AdminUserActionReq, client *m.ClientInfo) *m.Err {
+	userDb, errs := l.adminGetUser(adminReq.UserId)
+	if errs != nil {
+		return errs
+	}
+	if userDb.DeletedAt != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Аккаунт удален",
+			Error:     errors.New("user deleted"),
+		}
+	}
+
+	// Ссылка привязана к новому password_changed_at, поэтому ранее отправленные ссылки сброса тоже перестают действовать.
+	changedAt, ok, err := l.storage.User.InvalidatePasswordById(userDb.Id)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !ok {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Аккаунт удален",
+			Error:     errors.New("user deleted"),
+		}
+	}
+	userDb.PasswordChangedAt = changedAt
+
+	if err := l.storage.Session.RevokeAllByUserId(userDb.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if err := l.userSendResetPassCode(userDb); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.logger.Infof("logic.AdminResetPassword: admin %d reset password of user %d", adminReq.AdminId, userDb.Id)
+	l.auditRecord(m.AuditEventAdminPasswordReset, adminReq.AdminId, userDb.Id, client, nil)
+	return nil
+}
+
+// Завершает все сессии пользователя.
+func (l *Logic) AdminRevokeSessions(adminReq *m.AdminUserActionReq, client *m.ClientInfo) *m.Err {
+	userDb, errs := l.adminGetUser(adminReq.UserId)
+	if errs != nil {
+		return errs
+	}
+
+	if err := l.storage.Session.RevokeAllByUserId(userDb.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.logger.Infof("logic.AdminRevokeSessions: admin %d revoked sessions of user %d", adminReq.AdminId, userDb.Id)
+	l.auditRecord(m.AuditEventAdminRevokeSessions, adminReq.AdminId, userDb.Id, client, nil)
+	return nil
+}
+
+// Удаляет аккаунт пользователя и завершает все его сессии. Как и при удалении самим пользователем,
+// аккаунт окончательно удаляется по истечении срока хранения (см. PurgeDeletedUsers).
+func (l *Logic) AdminDeleteUser(adminReq *m.AdminUserActionReq, client *m.ClientInfo) *m.Err {
+	if errs := l.adminCheckNotSelf(adminReq); errs != nil {
+		return errs
+	}
+
+	marked, err := l.storage.User.MarkDeletedById(adminReq.UserId)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !marked {
+		return &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден или уже удален",
+			Error:     errors.New("user not found or already deleted"),
+		}
+	}
+	l.statusCache.forget(adminReq.UserId)
+
+	if err := l.storage.Session.RevokeAllByUserId(adminReq.UserId); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.logger.Infof("logic.AdminDeleteUser: admin %d deleted user %d", adminReq.AdminId, adminReq.UserId)
+	l.auditRecord(m.AuditEventAdminDelete, adminReq.AdminId, adminReq.UserId, client, nil)
+	return nil
+}
+
+func (l *Logic) adminGetUser(userId int) (*m.User, *m.Err) {
+	userDb, exists, err := l.storage.User.GetById(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+	return userDb, nil
+}
+
+// Администратор не может заблокировать или удалить собственный аккаунт (и остаться без доступа).
+func (l *Logic) adminCheckNotSelf(adminReq *m.AdminUserActionReq) *m.Err {
+	if adminReq.AdminId == adminReq.UserId {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Нельзя применить это действие к своему аккаунту",
+			Error:     errors.New("admin action on own account"),
+		}
+	}
+	return nil
+}
+
+func adminUserResp(user *m.User) *m.AdminUserResp {
+	return &m.AdminUserResp{
+		Id:              user.Id,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerified:   user.EmailVerified,
+		Status:          user.Status,
+		StatusReason:    user.StatusReason,
+		StatusChangedAt: user.StatusChangedAt,
+		CreatedAt:       user.CreatedAt,
+		DeletedAt:       user.DeletedAt,
+	}
+}
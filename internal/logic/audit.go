@@ -0,0 +1,121 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+)
+
+const (
+	auditPageDefaultLimit = 50   // Размер страницы журнала по умолчанию
+	auditPageMaxLimit     = 200  // Наибольший размер страницы журнала
+	auditExportPageLimit  = 1000 // Размер страницы при выгрузке всего журнала пользователя
+)
+
+// Записывает событие в журнал безопасности. actorId - кто совершил действие, userId - чей аккаунт затронут.
+func (l *Logic) auditRecord(eventType string, actorId, userId int, client *m.ClientInfo, metadata map[string]any) {
+	event := &m.AuditEvent{
+		Type:     eventType,
+		ActorId:  actorId,
+		UserId:   userId,
+		Metadata: metadata,
+	}
+	if client != nil {
+		event.Ip, event.UserAgent, event.RequestId = client.Ip, client.UserAgent, client.RequestId
+	}
+
+	l.audit.Record(event)
+}
+
+// Записывает неудачную попытку входа. Почта известного аккаунта не сохраняется: его определяет user_id.
+func (l *Logic) auditLoginFailure(userId int, client *m.ClientInfo, email, reason string) {
+	metadata := map[string]any{"reason": reason}
+	if userId == 0 && email != "" {
+		metadata["email_hmac"] = l.auditEmailHmac(email)
+	}
+	l.auditRecord(m.AuditEventLoginFailure, 0, userId, client, metadata)
+}
+
+// Обезличивает почту, которая может не принадлежать ни одному аккаунту (попытка входа, запрос на регистрацию
+// или сброс пароля). По HMAC можно связать события с одной почтой, но без ключа нельзя узнать саму почту.
+func (l *Logic) auditEmailHmac(email string) string {
+	return hashes.HmacToken(strings.ToLower(email), l.auditKey)
+}
+
+// Возвращает страницу журнала событий аккаунта пользователя.
+func (l *Logic) UserAuditEvents(userId int, filter *m.AuditFilter) (*m.AuditEventsResp, *m.Err) {
+	filter.UserId = userId
+	return l.auditEvents(filter)
+}
+
+// Возвращает страницу журнала событий всех пользователей (для администратора).
+func (l *Logic) AdminAuditEvents(filter *m.AuditFilter) (*m.AuditEventsResp, *m.Err) {
+	return l.auditEvents(filter)
+}
+
+func (l *Logic) auditEvents(filter *m.AuditFilter) (*m.AuditEventsResp, *m.Err) {
+	if filter.Limit <= 0 {
+		filter.Limit = auditPageDefaultLimit
+	}
+	if filter.Limit > auditPageMaxLimit || filter.Before < 0 || filter.UserId < 0 {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: fmt.Sprintf("Размер страницы - не больше %d", auditPageMaxLimit),
+			Error:     fmt.Errorf("invalid page: limit %d, before %d", filter.Limit, filter.Before),
+		}
+	}
+
+	events, err := l.audit.Events(filter)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	resp := &m.AuditEventsResp{Events: auditEventsResp(events)}
+	if len(events) == filter.Limit {
+		resp.NextBefore = events[len(events)-1].Id
+	}
+	return resp, nil
+}
+
+// Возвращает весь журнал событий аккаунта пользователя (для выгрузки данных).
+func (l *Logic) auditAllUserEvents(userId int) ([]*m.AuditEventResp, error) {
+	resp := make([]*m.AuditEventResp, 0)
+	filter := &m.AuditFilter{UserId: userId, Limit: auditExportPageLimit}
+	for {
+		events, err := l.audit.Events(filter)
+		if err != nil {
+			return nil, fmt.Errorf("logic.auditAllUserEvents(1): %w", err)
+		}
+		resp = append(resp, auditEventsResp(events)...)
+		if len(events) < filter.Limit {
+			return resp, nil
+		}
+		filter.Before = events[len(events)-1].Id
+	}
+}
+
+func auditEventsResp(events []*m.AuditEvent) []*m.AuditEventResp {
+	resp := make([]*m.AuditEventResp, 0, len(events))
+	for _, event := range events {
+		resp = append(resp, &m.AuditEventResp{
+			Id:        event.Id,
+			Type:      event.Type,
+			ActorId:   event.ActorId,
+			UserId:    event.UserId,
+			Ip:        event.Ip,
+			UserAgent: event.UserAgent,
+			RequestId: event.RequestId,
+			Metadata:  event.Metadata,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,63 @@
+package logic
+
+import (
+	"sync"
+	"time"
+)
+
+// Кэш с ограниченным временем жизни записей: избавляет от запроса к базе при проверке каждого access-токена.
+// Устаревшие записи удаляются при добавлении новых, не чаще раза за время жизни записи.
+type ttlCache[V any] struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[int]ttlCacheEntry[V]
+	nextSweep time.Time
+}
+
+type ttlCacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newTtlCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:     ttl,
+		entries: make(map[int]ttlCacheEntry[V]),
+	}
+}
+
+func (c *ttlCache[V]) get(key int) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[V]) set(key int, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.After(c.nextSweep) {
+		for cachedKey, cached := range c.entries {
+			if now.After(cached.expiresAt) {
+				delete(c.entries, cachedKey)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+
+	c.entries[key] = ttlCacheEntry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Удаляет запись, чтобы изменение сразу вступило в силу (на этом экземпляре сервиса).
+func (c *ttlCache[V]) forget(key int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,23 @@
+package logic
+
+import (
+	"fmt"
+	"time"
+)
+
+// Удаляет записи об истекших одноразовых кодах. Запускается по расписанию.
+func (l *Logic) PurgeConsumedTokens() error {
+	deleted, err := l.storage.ConsumedToken.DeleteExpired()
+	if err != nil {
+		return fmt.Errorf("logic.PurgeConsumedTokens(1): %w", err)
+	}
+	if deleted > 0 {
+		l.logger.Debugf("logic.PurgeConsumedTokens: %d expired tokens deleted", deleted)
+	}
+	return nil
+}
+
+// Как часто нужно запускать PurgeConsumedTokens.
+func (l *Logic) PurgeConsumedTokensInterval() time.Duration {
+	return consumedTokensPurge
+}
@@ -0,0 +1,142 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+	"github.com/lesienchik/vk__test/pkg/useragent"
+)
+
+// Запоминает устройство (user agent и ip), с которого вошел пользователь. Если с этого устройства
+// пользователь раньше не входил, отправляет ему на почту уведомление с одноразовой ссылкой для завершения
+// всех сессий. Самое первое устройство запоминается без уведомления. Ошибки только логируются: вход при этом
+// не прерывается.
+func (l *Logic) deviceCheckNew(userId int, client *m.ClientInfo) {
+	loginAt := time.Now()
+	isNew, err := l.storage.KnownDevice.Touch(&m.KnownDevice{
+		UserId:    userId,
+		UserAgent: client.UserAgent,
+		Ip:        client.Ip,
+	})
+	if err != nil {
+		l.logger.Error(fmt.Errorf("logic.deviceCheckNew(1): %w", err))
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	count, err := l.storage.KnownDevice.CountByUserId(userId)
+	if err != nil {
+		l.logger.Error(fmt.Errorf("logic.deviceCheckNew(2): %w", err))
+		return
+	}
+	if count <= 1 {
+		return
+	}
+
+	userDb, exists, err := l.storage.User.GetById(userId)
+	if err != nil {
+		l.logger.Error(fmt.Errorf("logic.deviceCheckNew(3): %w", err))
+		return
+	}
+	if !exists {
+		l.logger.Errorf("logic.deviceCheckNew: user %d not found", userId)
+		return
+	}
+
+	jti, err := hashes.GenRandomToken(jtiSize)
+	if err != nil {
+		l.logger.Error(fmt.Errorf("logic.deviceCheckNew(4): %w", err))
+		return
+	}
+	revokeCode, err := hashes.HmacGenHash(m.UserRevokeSessionsCode{
+		Id:      userDb.Id,
+		Purpose: codePurposeRevoke,
+		Jti:     jti,
+	}, newDeviceLinkExpires, l.keyRing)
+	if err != nil {
+		l.logger.Error(fmt.Errorf("logic.deviceCheckNew(5): %w", err))
+		return
+	}
+
+	device := useragent.Describe(client.UserAgent)
+	l.auditRecord(m.AuditEventNewDevice, userDb.Id, userDb.Id, client, map[string]any{"device": device})
+
+	go func() {
+		if err := l.email.SendNewDeviceNotice(userDb.Email, device, client.Ip, loginAt, revokeCode); err != nil {
+			l.logger.Error(fmt.Errorf("logic.deviceCheckNew(6): %w", err))
+		}
+	}()
+}
+
+// Завершает все сессии пользователя по ссылке "это был не я" из уведомления о входе с нового устройства.
+// Ссылка одноразовая: ее jti запоминается в storage.ConsumedToken.
+func (l *Logic) UserRevokeSessionsByCode(userReq *m.UserRevokeSessionsReq, client *m.ClientInfo) *m.Err {
+	revokeCode := new(m.UserRevokeSessionsCode)
+	hashingStatus, err := hashes.HmacParseAndValidateHash(userReq.Code, revokeCode, l.keyRing)
+	if err != nil {
+		if hashingStatus == hashes.HashExpires {
+			return &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Срок действия ссылки истек. Завершите сессии в настройках аккаунта",
+				Error:     err,
+			}
+		}
+
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для завершения сессий",
+			Error:     err,
+		}
+	}
+	if revokeCode.Id <= 0 || revokeCode.Jti == "" || revokeCode.Purpose != codePurposeRevoke {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для завершения сессий",
+			Error:     errors.New("invalid revoke sessions code"),
+		}
+	}
+
+	consumed, errs := l.consumeCode(revokeCode.Jti, codePurposeRevoke, newDeviceLinkExpires)
+	if errs != nil {
+		return errs
+	}
+	if !consumed {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Ссылка для завершения сессий уже была использована",
+			Error:     errors.New("revoke sessions code has already been used"),
+		}
+	}
+
+	if err := l.storage.Session.RevokeAllByUserId(revokeCode.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.auditRecord(m.AuditEventSessionRevoke, revokeCode.Id, revokeCode.Id, client, map[string]any{"scope": "all", "via": "new_device_notice"})
+	return nil
+}
+
+// Отмечает одноразовый код jti использованным. Возвращает false, если код уже был использован.
+// ttl - срок действия кода при выдаче.
+func (l *Logic) consumeCode(jti, purpose string, ttl time.Duration) (bool, *m.Err) {
+	// Запись нужна, пока код действителен: его срок не больше ttl с текущего момента.
+	consumed, err := l.storage.ConsumedToken.Consume(jti, purpose, time.Now().Add(ttl))
+	if err != nil {
+		return false, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	return consumed, nil
+}
@@ -0,0 +1,225 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+	"github.com/lesienchik/vk__test/pkg/validator"
+)
+
+// Начинает смену почты: отправляет ссылку подтверждения на новый адрес и уведомление со ссылкой отмены на старый.
+// Почта меняется только после перехода по ссылке из письма (UserConfirmChangeEmail).
+func (l *Logic) UserChangeEmail(userReq *m.UserChangeEmailReq) *m.Err {
+	if !validator.IsValidEmail(userReq.NewEmail) {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный email",
+		}
+	}
+
+	userDb, exists, err := l.storage.User.GetById(userReq.Id)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	if _, err := l.hasher.Verify(userDb.Password, userReq.Password); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный текущий пароль",
+			Error:     err,
+		}
+	}
+	if strings.EqualFold(userReq.NewEmail, userDb.Email) {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Новая почта совпадает с текущей",
+			Error:     errors.New("new email equals current email"),
+		}
+	}
+
+	if errs := l.userCheckEmailFree(userReq.NewEmail); errs != nil {
+		return errs
+	}
+
+	createdAt, err := l.storage.EmailChange.Create(&m.EmailChange{
+		UserId:    userDb.Id,
+		OldEmail:  userDb.Email,
+		NewEmail:  userReq.NewEmail,
+		ExpiresAt: time.Now().Add(emailChangeExpiresTime),
+	})
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	confirmCode, err := hashes.HmacGenHash(m.UserChangeEmailCode{
+		Id:        userDb.Id,
+		Purpose:   codePurposeChangeEmail,
+		NewEmail:  userReq.NewEmail,
+		CreatedAt: createdAt.UnixMicro(),
+	}, emailChangeExpiresTime, l.keyRing)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	cancelCode, err := hashes.HmacGenHash(m.UserChangeEmailCode{
+		Id:        userDb.Id,
+		Purpose:   codePurposeCancelEmail,
+		NewEmail:  userReq.NewEmail,
+		CreatedAt: createdAt.UnixMicro(),
+	}, emailChangeExpiresTime, l.keyRing)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	go func() {
+		if err := l.email.SendChangeEmailCode(userReq.NewEmail, confirmCode); err != nil {
+			l.logger.Error(fmt.Errorf("logic.UserChangeEmail(1): %w", err))
+		}
+		if err := l.email.SendChangeEmailNotice(userDb.Email, userReq.NewEmail, cancelCode); err != nil {
+			l.logger.Error(fmt.Errorf("logic.UserChangeEmail(2): %w", err))
+		}
+	}()
+	return nil
+}
+
+// Меняет почту пользователя по коду из письма, отправленного на новый адрес.
+func (l *Logic) UserConfirmChangeEmail(code string) *m.Err {
+	changeCode, errs := l.userParseChangeEmailCode(code, codePurposeChangeEmail)
+	if errs != nil {
+		return errs
+	}
+
+	// За время ожидания адрес мог занять другой пользователь.
+	if errs := l.userCheckEmailFree(changeCode.NewEmail); errs != nil {
+		return errs
+	}
+
+	confirmed, err := l.storage.EmailChange.Confirm(changeCode.Id, changeCode.NewEmail, time.UnixMicro(changeCode.CreatedAt))
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !confirmed {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Ссылка для смены почты уже использована или отменена",
+			Error:     errors.New("email change not found"),
+		}
+	}
+
+	// Как и после смены пароля, все устройства должны войти заново.
+	if err := l.storage.Session.RevokeAllByUserId(changeCode.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	return nil
+}
+
+// Отменяет смену почты по ссылке из уведомления, отправленного на старый адрес.
+func (l *Logic) UserCancelChangeEmail(code string) *m.Err {
+	changeCode, errs := l.userParseChangeEmailCode(code, codePurposeCancelEmail)
+	if errs != nil {
+		return errs
+	}
+
+	canceled, err := l.storage.EmailChange.Cancel(changeCode.Id, changeCode.NewEmail, time.UnixMicro(changeCode.CreatedAt))
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !canceled {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Смена почты уже подтверждена или отменена",
+			Error:     errors.New("email change not found"),
+		}
+	}
+	return nil
+}
+
+// Проверяет подпись, срок действия и назначение кода смены почты.
+func (l *Logic) userParseChangeEmailCode(code, purpose string) (*m.UserChangeEmailCode, *m.Err) {
+	changeCode := new(m.UserChangeEmailCode)
+	hashingStatus, err := hashes.HmacParseAndValidateHash(code, changeCode, l.keyRing)
+	if err != nil {
+		if hashingStatus == hashes.HashExpires {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Срок действия ссылки для смены почты истек",
+				Error:     err,
+			}
+		}
+
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для смены почты",
+			Error:     err,
+		}
+	}
+	if changeCode.Purpose != purpose {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для смены почты",
+			Error:     errors.New("invalid code purpose"),
+		}
+	}
+	return changeCode, nil
+}
+
+// Проверяет, что почта не занята другим пользователем (как при регистрации).
+func (l *Logic) userCheckEmailFree(email string) *m.Err {
+	_, exists, err := l.storage.User.GetByEmail(email)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if exists {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Пользователь с такой почтой уже существует",
+			Error:     errors.New("email already exists"),
+		}
+	}
+	return nil
+}
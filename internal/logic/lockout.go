@@ -0,0 +1,187 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/lesienchik/vk__test/internal/config"
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// Значения по умолчанию для защиты от перебора паролей (если не заданы в конфиге).
+const (
+	defaultMaxAccountFailures = 5
+	defaultMaxIpFailures      = 50
+	defaultLockoutWindow      = 15 * 60 // сек
+	defaultLockoutTime        = 15 * 60 // сек
+	defaultLockoutBaseDelay   = 500     // мс
+	defaultLockoutMaxDelay    = 30_000  // мс
+)
+
+// Счетчик неудачных попыток и порог, после которого вход блокируется.
+type lockoutKey struct {
+	key         string
+	maxFailures int
+	shared      bool // Счетчик по ip: общий для всех аккаунтов, успешный вход его не сбрасывает
+	failures    int  // Значение счетчика с учетом текущей попытки (заполняет lockoutCheck)
+}
+
+// Заполняет незаданные параметры блокировки значениями по умолчанию.
+func lockoutWithDefaults(cfg config.Lockout) config.Lockout {
+	if cfg.MaxAccountFailures <= 0 {
+		cfg.MaxAccountFailures = defaultMaxAccountFailures
+	}
+	if cfg.MaxIpFailures <= 0 {
+		cfg.MaxIpFailures = defaultMaxIpFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultLockoutWindow
+	}
+	if cfg.LockoutTime <= 0 {
+		cfg.LockoutTime = defaultLockoutTime
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultLockoutBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultLockoutMaxDelay
+	}
+	return cfg
+}
+
+// Счетчики для входа по паролю: по аккаунту (почте) и по ip клиента.
+func (l *Logic) lockoutAuthKeys(email, ip string) []lockoutKey {
+	return []lockoutKey{
+		{key: lockoutAccountKey(email), maxFailures: l.lockout.MaxAccountFailures},
+		{key: "ip:" + ip, maxFailures: l.lockout.MaxIpFailures, shared: true},
+	}
+}
+
+// Ключ счетчика (и журнала блокировок) по аккаунту.
+func lockoutAccountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+// Счетчики для второго шага аутентификации (ввод TOTP-кода).
+func (l *Logic) lockout2faKeys(userId int, ip string) []lockoutKey {
+	return []lockoutKey{
+		{key: fmt.Sprintf("2fa:%d", userId), maxFailures: l.lockout.MaxAccountFailures},
+		{key: "ip:" + ip, maxFailures: l.lockout.MaxIpFailures, shared: true},
+	}
+}
+
+// Засчитывает попытку входа до проверки пароля: счетчики сначала атомарно увеличиваются в хранилище
+// и только потом сравниваются с порогом, поэтому параллельные запросы не могут проскочить лимит.
+// Если по одному из счетчиков действует задержка или блокировка, попытка отклоняется и не засчитывается.
+// Исход разрешенной попытки нужно передать в lockoutFail, lockoutReset или lockoutRelease.
+func (l *Logic) lockoutCheck(keys []lockoutKey) *m.Err {
+	window := time.Duration(l.lockout.Window) * time.Second
+	for i := range keys {
+		attempt, err := l.storage.LoginAttempt.Fail(keys[i].key, window)
+		if err != nil {
+			l.lockoutRelease(keys[:i])
+			return &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+		keys[i].failures = attempt.Failures
+
+		wait := time.Until(attempt.BlockedUntil)
+		if wait <= 0 && attempt.Failures <= keys[i].maxFailures {
+			continue
+		}
+		l.lockoutRelease(keys[:i+1])
+
+		// Порог уже превышен параллельными попытками, а блокировка еще не записана.
+		maxDelay := time.Duration(l.lockout.MaxDelay) * time.Millisecond
+		if wait <= 0 {
+			wait = maxDelay
+		}
+
+		clientMsg := "Слишком много попыток входа. Повторите через несколько секунд"
+		if wait > maxDelay {
+			clientMsg = "Вход временно заблокирован из-за большого количества неудачных попыток. Попробуйте позже"
+		}
+		return &m.Err{
+			Code:       fasthttp.StatusTooManyRequests,
+			ClientMsg:  clientMsg,
+			Error:      errors.New("login attempts are throttled"),
+			RetryAfter: int(math.Ceil(wait.Seconds())),
+		}
+	}
+	return nil
+}
+
+// Обрабатывает неудачную попытку (уже засчитанную lockoutCheck): с каждой неудачей задержка до следующей
+// попытки растет вдвое, а после maxFailures неудач вход блокируется на lockout_time (блокировка записывается в журнал).
+// Ошибки хранилища только логируются, чтобы не подменять ими ответ о неверных данных.
+func (l *Logic) lockoutFail(keys []lockoutKey, ip string) {
+	for _, k := range keys {
+		if k.failures >= k.maxFailures {
+			lockedUntil := time.Now().Add(time.Duration(l.lockout.LockoutTime) * time.Second)
+			if err := l.storage.LoginAttempt.Lock(k.key, lockedUntil); err != nil {
+				l.logger.Error(fmt.Errorf("logic.lockoutFail(1): %w", err))
+				continue
+			}
+
+			err := l.storage.LoginLockout.Create(&m.LoginLockout{
+				Key:         k.key,
+				Ip:          ip,
+				Failures:    k.failures,
+				LockedUntil: lockedUntil,
+			})
+			if err != nil {
+				l.logger.Error(fmt.Errorf("logic.lockoutFail(2): %w", err))
+			}
+			l.logger.Warnf("logic.lockoutFail: login locked for %s until %s", k.key, lockedUntil.Format(time.RFC3339))
+			continue
+		}
+
+		if err := l.storage.LoginAttempt.Block(k.key, time.Now().Add(l.lockoutDelay(k.failures))); err != nil {
+			l.logger.Error(fmt.Errorf("logic.lockoutFail(3): %w", err))
+		}
+	}
+}
+
+// Обрабатывает успешный вход: сбрасывает счетчик аккаунта (или 2FA). Счетчик по ip не сбрасывается,
+// иначе владелец одного аккаунта мог бы обнулять его между попытками подбора к чужим аккаунтам:
+// с него только снимается текущая попытка, а прошлые неудачи истекут вместе с окном подсчета.
+func (l *Logic) lockoutReset(keys []lockoutKey) {
+	for _, k := range keys {
+		if k.shared {
+			if err := l.storage.LoginAttempt.Release(k.key); err != nil {
+				l.logger.Error(fmt.Errorf("logic.lockoutReset(1): %w", err))
+			}
+			continue
+		}
+		if err := l.storage.LoginAttempt.Reset(k.key); err != nil {
+			l.logger.Error(fmt.Errorf("logic.lockoutReset(2): %w", err))
+		}
+	}
+}
+
+// Снимает засчитанную попытку, если проверить данные не удалось (например, из-за ошибки хранилища).
+func (l *Logic) lockoutRelease(keys []lockoutKey) {
+	for _, k := range keys {
+		if err := l.storage.LoginAttempt.Release(k.key); err != nil {
+			l.logger.Error(fmt.Errorf("logic.lockoutRelease(1): %w", err))
+		}
+	}
+}
+
+// Задержка после failures неудач: base_delay * 2^(failures-1), но не больше max_delay.
+func (l *Logic) lockoutDelay(failures int) time.Duration {
+	delay := time.Duration(l.lockout.BaseDelay) * time.Millisecond
+	maxDelay := time.Duration(l.lockout.MaxDelay) * time.Millisecond
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
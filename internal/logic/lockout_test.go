@@ -0,0 +1,185 @@
+package logic
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesienchik/vk__test/internal/config"
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/internal/storage"
+)
+
+// Журнал блокировок в памяти (в тестах нет базы).
+type testLoginLockout struct {
+	lockouts []*m.LoginLockout
+}
+
+func (t *testLoginLockout) Create(lockout *m.LoginLockout) error {
+	t.lockouts = append(t.lockouts, lockout)
+	return nil
+}
+
+func (t *testLoginLockout) GetByKey(key string) ([]*m.LoginLockout, error) {
+	return t.lockouts, nil
+}
+
+func newTestLockoutLogic(cfg config.Lockout) *Logic {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return &Logic{
+		lockout: lockoutWithDefaults(cfg),
+		logger:  logger,
+		storage: &storage.Storage{
+			LoginAttempt: storage.NewLoginAttemptMemory(),
+			LoginLockout: &testLoginLockout{},
+		},
+	}
+}
+
+// Попытка входа по паролю: success - верны ли данные.
+func testLoginAttempt(l *Logic, success bool) *m.Err {
+	keys := l.lockoutAuthKeys("user@mail.ru", "127.0.0.1")
+	if errs := l.lockoutCheck(keys); errs != nil {
+		return errs
+	}
+
+	if success {
+		l.lockoutReset(keys)
+	} else {
+		l.lockoutFail(keys, "127.0.0.1")
+	}
+	return nil
+}
+
+func TestLockoutDelay(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	l := newTestLockoutLogic(config.Lockout{BaseDelay: 500, MaxDelay: 30_000})
+
+	testTable := []struct {
+		desc     string        // Описание теста
+		input    int           // Количество неудач
+		expected time.Duration // Ожидаемый результат выполнения теста
+	}{
+		{
+			desc:     "First failure",
+			input:    1,
+			expected: 500 * time.Millisecond,
+		},
+		{
+			desc:     "Doubles with every failure",
+			input:    2,
+			expected: time.Second,
+		},
+		{
+			desc:     "Doubles with every failure",
+			input:    4,
+			expected: 4 * time.Second,
+		},
+		{
+			desc:     "Capped by max delay",
+			input:    7, // 500мс * 2^6 = 32с
+			expected: 30 * time.Second,
+		},
+		{
+			desc:     "Capped by max delay",
+			input:    100,
+			expected: 30 * time.Second,
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d (%s)", number, testCase.desc)
+
+		actual := l.lockoutDelay(testCase.input)
+		// Assert
+		requires.Equal(testCase.expected, actual)
+	}
+}
+
+func TestLockoutCheck(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	// Задержка после неудачи - 10мс, после трех неудач вход блокируется на минуту.
+	cfg := config.Lockout{MaxAccountFailures: 3, BaseDelay: 10, MaxDelay: 10, LockoutTime: 60}
+	pause := 15 * time.Millisecond // Больше задержки после неудачи
+
+	testTable := []struct {
+		desc          string        // Описание теста
+		failures      int           // Неудачных попыток (с паузами между ними)
+		success       bool          // Успешный вход после неудач
+		failuresAfter int           // Неудачных попыток после успешного входа
+		wait          time.Duration // Пауза перед проверяемой попыткой
+		expected      bool          // Ожидаемый результат выполнения теста (попытка разрешена)
+		expectedRetry int           // Ожидаемое время до повтора (сек)
+	}{
+		{
+			desc:     "Success",
+			expected: true,
+		},
+		{
+			desc:     "Below threshold after delay",
+			failures: 2,
+			wait:     pause,
+			expected: true,
+		},
+		{
+			desc:          "Delayed right after failure",
+			failures:      1,
+			expected:      false,
+			expectedRetry: 1,
+		},
+		{
+			desc:          "Locked at threshold",
+			failures:      3,
+			wait:          pause,
+			expected:      false,
+			expectedRetry: 60,
+		},
+		{
+			desc:          "Success resets account counter",
+			failures:      2,
+			success:       true,
+			failuresAfter: 2, // Без сброса это были бы третья и четвертая неудачи подряд
+			wait:          pause,
+			expected:      true,
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d (%s)", number, testCase.desc)
+
+		l := newTestLockoutLogic(cfg)
+		for i := 0; i < testCase.failures; i++ {
+			time.Sleep(pause)
+			requires.Nil(testLoginAttempt(l, false))
+		}
+		if testCase.success {
+			time.Sleep(pause)
+			requires.Nil(testLoginAttempt(l, true))
+		}
+		for i := 0; i < testCase.failuresAfter; i++ {
+			time.Sleep(pause)
+			requires.Nil(testLoginAttempt(l, false))
+		}
+
+		time.Sleep(testCase.wait)
+		errs := l.lockoutCheck(l.lockoutAuthKeys("user@mail.ru", "127.0.0.1"))
+		// Assert
+		if testCase.expected {
+			requires.Nil(errs)
+			continue
+		}
+		requires.NotNil(errs)
+		requires.Equal(429, errs.Code)
+		requires.Equal(testCase.expectedRetry, errs.RetryAfter)
+	}
+}
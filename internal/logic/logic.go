@@ -5,32 +5,85 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/lesienchik/vk__test/internal/audit"
+	"github.com/lesienchik/vk__test/internal/config"
+	m "github.com/lesienchik/vk__test/internal/models"
 	"github.com/lesienchik/vk__test/internal/storage"
 	"github.com/lesienchik/vk__test/pkg/email"
+	"github.com/lesienchik/vk__test/pkg/hashes"
 )
 
 // Популярные клиентские сообщения для ошибок.
 var (
-	msgInternalServerError = "Упс! Что-то пошло не так..."
+	msgInternalServerError = m.MsgInternalServerError
 )
 
 const (
 	jwtExpiresAccessTime  = 10 * time.Minute
 	jwtExpiresRefreshTime = 24 * time.Hour * 30
+	jwtTypeAccess         = "access"  // Значение claim typ access-токена
+	jwtTypeRefresh        = "refresh" // Значение claim typ refresh-токена
+
+	registrationExpiresTime = 10 * time.Minute // Время жизни неподтвержденной регистрации
+	emailChangeExpiresTime  = time.Hour        // Время жизни неподтвержденной смены почты
+	newDeviceLinkExpires    = 24 * time.Hour   // Время жизни ссылки из уведомления о входе с нового устройства
+	consumedTokensPurge     = time.Hour        // Как часто удалять записи об истекших одноразовых кодах
+	verifyCodeSize          = 32               // Размер кода подтверждения в байтах
+	jtiSize                 = 16               // Размер идентификатора refresh-токена в байтах
+
+	totpIssuer           = "vktest"
+	recoveryCodesCount   = 10              // Количество одноразовых кодов восстановления 2FA
+	recoveryCodeSize     = 5               // Размер кода восстановления в байтах
+	challengeExpiresTime = 5 * time.Minute // Время жизни challenge-токена второго шага аутентификации
+)
+
+// Назначение подписанных кодов (HmacGenHash): не позволяет использовать код одного типа вместо другого.
+const (
+	codePurposeResetPass   = "reset_password"
+	codePurpose2fa         = "2fa"
+	codePurposeChangeEmail = "change_email"
+	codePurposeCancelEmail = "cancel_email_change"
+	codePurposeRestore     = "restore_account"
+	codePurposeRevoke      = "revoke_sessions"
+	codePurposeMagicLink   = "magic_link"
 )
 
 type Logic struct {
-	secret  string
-	logger  *logrus.Logger
-	email   *email.Email
-	storage *storage.Storage
+	strictRefresh bool
+	lockout       config.Lockout
+	deletion      config.Deletion
+	jwt           config.Jwt
+	statusCache   *ttlCache[userStatusEntry]
+	sessionCache  *ttlCache[sessionEntry]
+	hasher        *hashes.PasswordHasher
+	keyRing       *hashes.KeyRing
+	audit         *audit.Auditor
+	auditKey      []byte // Ключ для обезличивания почты в журнале (SECRET_KEY)
+	logger        *logrus.Logger
+	email         *email.Email
+	storage       *storage.Storage
 }
 
-func New(secret string, logger *logrus.Logger, email *email.Email, storage *storage.Storage) *Logic {
+func New(cfg *config.Logic, logger *logrus.Logger, email *email.Email, storage *storage.Storage, hasher *hashes.PasswordHasher,
+	keyRing *hashes.KeyRing, audit *audit.Auditor) *Logic {
 	return &Logic{
-		secret:  secret,
-		logger:  logger,
-		email:   email,
-		storage: storage,
+		strictRefresh: cfg.StrictRefresh,
+		lockout:       lockoutWithDefaults(cfg.Lockout),
+		deletion:      deletionWithDefaults(cfg.Deletion),
+		jwt:           jwtWithDefaults(cfg.Jwt),
+		statusCache:   newTtlCache[userStatusEntry](statusCacheTtl(cfg.StatusCache)),
+		sessionCache:  newTtlCache[sessionEntry](statusCacheTtl(cfg.StatusCache)),
+		hasher:        hasher,
+		keyRing:       keyRing,
+		audit:         audit,
+		auditKey:      []byte(cfg.SecretKey),
+		logger:        logger,
+		email:         email,
+		storage:       storage,
 	}
 }
+
+// Возвращает открытые ключи для проверки jwt другими сервисами.
+func (l *Logic) Jwks() *hashes.Jwks {
+	return l.keyRing.Jwks()
+}
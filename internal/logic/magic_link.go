@@ -0,0 +1,139 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+	"github.com/lesienchik/vk__test/pkg/validator"
+)
+
+// Отправляет на почту пользователя одноразовую ссылку для входа без пароля.
+// Как и при сбросе пароля, ответ не зависит от того, существует ли пользователь с такой почтой.
+func (l *Logic) UserMagicLink(userReq *m.UserMagicLinkReq, client *m.ClientInfo) *m.Err {
+	if !validator.IsValidEmail(userReq.Email) {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный email",
+		}
+	}
+
+	userDb, exists, err := l.storage.User.GetByEmail(userReq.Email)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists || userDb.DeletedAt != nil || userDb.Status != m.UserStatusActive {
+		l.logger.Debugf("logic.UserMagicLink: active user with email %s not found", userReq.Email)
+		l.auditRecord(m.AuditEventMagicLinkRequest, 0, 0, client, map[string]any{"email_hmac": l.auditEmailHmac(userReq.Email), "user_found": false})
+		return nil
+	}
+	l.auditRecord(m.AuditEventMagicLinkRequest, 0, userDb.Id, client, map[string]any{"user_found": true})
+
+	jti, err := hashes.GenRandomToken(jtiSize)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	magicCode, err := hashes.HmacGenHash(m.UserMagicLinkCode{
+		Id:      userDb.Id,
+		Purpose: codePurposeMagicLink,
+		Jti:     jti,
+	}, hashes.ExpiresFiveMinute, l.keyRing)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	go func() {
+		if err := l.email.SendMagicLink(userDb.Email, magicCode); err != nil {
+			l.logger.Error(fmt.Errorf("logic.UserMagicLink: %w", err))
+		}
+	}()
+	return nil
+}
+
+// Аутентифицирует пользователя по ссылке из письма. Ссылка одноразовая: ее jti запоминается в storage.ConsumedToken.
+// Ссылка заменяет только пароль: при включенной 2FA, как и в UserAuth, возвращается challenge-токен для второго шага.
+func (l *Logic) UserMagicLinkConfirm(userReq *m.UserMagicLinkConfirmReq, client *m.ClientInfo) (int, string, *m.Err) {
+	magicCode := new(m.UserMagicLinkCode)
+	hashingStatus, err := hashes.HmacParseAndValidateHash(userReq.Code, magicCode, l.keyRing)
+	if err != nil {
+		if hashingStatus == hashes.HashExpires {
+			return -1, "", &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Срок действия ссылки для входа истек, запросите новую",
+				Error:     err,
+			}
+		}
+
+		return -1, "", &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для входа",
+			Error:     err,
+		}
+	}
+	if magicCode.Id <= 0 || magicCode.Jti == "" || magicCode.Purpose != codePurposeMagicLink {
+		return -1, "", &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для входа",
+			Error:     errors.New("invalid magic link code"),
+		}
+	}
+
+	consumed, errs := l.consumeCode(magicCode.Jti, codePurposeMagicLink, hashes.ExpiresFiveMinute)
+	if errs != nil {
+		return -1, "", errs
+	}
+	if !consumed {
+		l.auditLoginFailure(magicCode.Id, client, "", "magic_link_used")
+		return -1, "", &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Ссылка для входа уже была использована",
+			Error:     errors.New("magic link has already been used"),
+		}
+	}
+
+	userDb, exists, err := l.storage.User.GetById(magicCode.Id)
+	if err != nil {
+		return -1, "", &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists || userDb.DeletedAt != nil {
+		l.auditLoginFailure(magicCode.Id, client, "", "deleted")
+		return -1, "", &m.Err{
+			Code:      fasthttp.StatusForbidden,
+			ClientMsg: "Аккаунт удален",
+			Error:     errors.New("user not found or deleted"),
+		}
+	}
+	if errs := userCheckStatus(userDb.Status); errs != nil {
+		l.auditLoginFailure(userDb.Id, client, "", userDb.Status)
+		return -1, "", errs
+	}
+
+	challenge, errs := l.twoFactorChallenge(userDb.Id)
+	if errs != nil {
+		return -1, "", errs
+	}
+	if challenge == "" {
+		l.auditRecord(m.AuditEventLoginSuccess, userDb.Id, userDb.Id, client, map[string]any{"method": "magic_link"})
+		l.deviceCheckNew(userDb.Id, client)
+	}
+	return userDb.Id, challenge, nil
+}
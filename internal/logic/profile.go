@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"errors"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/validator"
+)
+
+// Возвращает профиль пользователя (без хэша пароля).
+func (l *Logic) UserProfile(userId int) (*m.UserProfileResp, *m.Err) {
+	userDb, exists, err := l.storage.User.GetById(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	userTotp, exists, err := l.storage.Totp.GetByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	return &m.UserProfileResp{
+		Id:               userDb.Id,
+		Username:         userDb.Username,
+		Email:            userDb.Email,
+		CreatedAt:        userDb.CreatedAt,
+		EmailVerified:    userDb.EmailVerified,
+		TwoFactorEnabled: exists && userTotp.Enabled,
+	}, nil
+}
+
+// Изменяет поля профиля, переданные в запросе. Почта меняется отдельно, с подтверждением нового адреса.
+func (l *Logic) UserUpdateProfile(userReq *m.UserUpdateReq) (*m.UserProfileResp, *m.Err) {
+	userDb, exists, err := l.storage.User.GetById(userReq.Id)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	if userReq.Username != nil && *userReq.Username != userDb.Username {
+		if !validator.IsValidUsername(*userReq.Username) {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Псевдоним не удовлетворяет требованиям",
+			}
+		}
+
+		// Проверяем псевдоним на занятость.
+		_, exists, err := l.storage.User.GetByUsername(*userReq.Username)
+		if err != nil {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+		if exists {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Пользователь с таким псевдонимом уже существует",
+				Error:     errors.New("username already exists"),
+			}
+		}
+
+		// Псевдоним мог занять другой запрос между проверкой и обновлением: это ловит уникальный индекс.
+		updated, err := l.storage.User.UpdateUsernameById(userReq.Id, *userReq.Username)
+		if err != nil {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+		if !updated {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Пользователь с таким псевдонимом уже существует",
+				Error:     errors.New("username already exists"),
+			}
+		}
+	}
+	return l.UserProfile(userReq.Id)
+}
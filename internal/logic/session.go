@@ -0,0 +1,244 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+)
+
+type sessionEntry struct {
+	userId int
+	active bool
+}
+
+// Проверяет, что сессия access-токена принадлежит пользователю, не отозвана и не истекла. Состояние берется
+// из кэша sessionCache, поэтому завершение сессии вступает в силу для уже выданных access-токенов
+// с задержкой до logic.status_cache (на этом экземпляре сервиса при завершении одной сессии - сразу).
+func (l *Logic) sessionCheckActive(userId, sessionId int) *m.Err {
+	if sessionId <= 0 {
+		return &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("access token has no session id"),
+		}
+	}
+
+	entry, ok := l.sessionCache.get(sessionId)
+	if !ok {
+		session, exists, err := l.storage.Session.GetById(sessionId)
+		if err != nil {
+			return &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+
+		if exists {
+			entry = sessionEntry{userId: session.UserId, active: session.RevokedAt == nil && time.Now().Before(session.ExpiresAt)}
+		}
+		l.sessionCache.set(sessionId, entry)
+	}
+
+	if !entry.active || entry.userId != userId {
+		return &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("session not found or revoked"),
+		}
+	}
+	return nil
+}
+
+// Проверяет сессию refresh-токена и выполняет ротацию: возвращает новый refresh-токен того же семейства и id сессии.
+// Повторное предъявление уже выведенного из оборота токена отзывает все семейство (сессию) целиком.
+func (l *Logic) sessionRotate(refreshClaims *m.UserAuthClaims) (string, int, *m.Err) {
+	if refreshClaims.ID == "" {
+		return "", -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("refresh token has no jti"),
+		}
+	}
+
+	session, exists, err := l.storage.Session.GetByJti(refreshClaims.ID)
+	if err != nil {
+		return "", -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return "", -1, l.sessionCheckReuse(refreshClaims.ID)
+	}
+
+	if session.UserId != refreshClaims.Id {
+		return "", -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("session not found"),
+		}
+	}
+	if session.RevokedAt != nil {
+		return "", -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("session has been revoked"),
+		}
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return "", -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("session has expired"),
+		}
+	}
+
+	newJti, err := hashes.GenRandomToken(jtiSize)
+	if err != nil {
+		return "", -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	// Срок жизни семейства не продлевается: новый токен истекает вместе с сессией.
+	newRefresh, err := l.userGenRefreshToken(session.UserId, newJti, session.ExpiresAt)
+	if err != nil {
+		return "", -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	rotated, err := l.storage.Session.Rotate(session.Jti, newJti)
+	if err != nil {
+		return "", -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !rotated {
+		// Токен успели использовать параллельно - это тоже повторное предъявление.
+		return "", -1, l.sessionCheckReuse(session.Jti)
+	}
+	return newRefresh, session.Id, nil
+}
+
+// Обрабатывает refresh-токен, которого нет среди текущих. Если токен уже выводился из оборота,
+// считаем его украденным и отзываем всю сессию.
+func (l *Logic) sessionCheckReuse(jti string) *m.Err {
+	session, exists, err := l.storage.Session.GetByRetiredJti(jti)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("session not found"),
+		}
+	}
+
+	if err := l.storage.Session.RevokeById(session.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.logger.Warnf("logic.sessionCheckReuse: refresh token reuse detected, session %d of user %d revoked", session.Id, session.UserId)
+
+	return &m.Err{
+		Code:  fasthttp.StatusBadRequest,
+		Error: errors.New("refresh token reuse detected"),
+	}
+}
+
+// Завершает сессию, к которой относится refresh-токен.
+func (l *Logic) UserLogout(refresh string, client *m.ClientInfo) *m.Err {
+	refreshClaims, _, err := l.userParseToken(refresh, jwtTypeRefresh)
+	if err != nil {
+		return &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: fmt.Errorf("invalid refresh token: %w", err),
+		}
+	}
+
+	if err := l.storage.Session.RevokeByJti(refreshClaims.ID); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.auditRecord(m.AuditEventSessionRevoke, refreshClaims.Id, refreshClaims.Id, client, map[string]any{"scope": "current"})
+	return nil
+}
+
+// Завершает все сессии пользователя.
+func (l *Logic) UserLogoutAll(userId int, client *m.ClientInfo) *m.Err {
+	if err := l.storage.Session.RevokeAllByUserId(userId); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.auditRecord(m.AuditEventSessionRevoke, userId, userId, client, map[string]any{"scope": "all"})
+	return nil
+}
+
+// Возвращает активные сессии (устройства) пользователя. Текущая сессия помечается флагом Current.
+func (l *Logic) UserSessions(userId, currentSessionId int) ([]*m.UserSessionResp, *m.Err) {
+	sessions, err := l.storage.Session.GetActiveByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	resp := make([]*m.UserSessionResp, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, &m.UserSessionResp{
+			Id:         session.Id,
+			UserAgent:  session.UserAgent,
+			Ip:         session.Ip,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.Id == currentSessionId,
+		})
+	}
+	return resp, nil
+}
+
+// Завершает одну из сессий пользователя (например, на потерянном устройстве).
+func (l *Logic) UserRevokeSession(userId, sessionId int, client *m.ClientInfo) *m.Err {
+	revoked, err := l.storage.Session.RevokeByUserIdAndId(userId, sessionId)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !revoked {
+		return &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Сессия не найдена",
+			Error:     errors.New("active session not found"),
+		}
+	}
+	l.sessionCache.forget(sessionId)
+	l.auditRecord(m.AuditEventSessionRevoke, userId, userId, client, map[string]any{"scope": "session", "session_id": sessionId})
+	return nil
+}
@@ -0,0 +1,95 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+const defaultStatusCache = 30 // сек
+
+// Допустимые переходы между состояниями аккаунта.
+var userStatusTransitions = map[string][]string{
+	m.UserStatusPending:   {m.UserStatusActive, m.UserStatusBanned},
+	m.UserStatusActive:    {m.UserStatusSuspended, m.UserStatusBanned},
+	m.UserStatusSuspended: {m.UserStatusActive, m.UserStatusBanned},
+	m.UserStatusBanned:    {m.UserStatusActive},
+}
+
+// Сообщения клиенту для неактивных аккаунтов.
+var userStatusClientMsgs = map[string]string{
+	m.UserStatusPending:   "Аккаунт еще не активирован",
+	m.UserStatusSuspended: "Аккаунт временно заблокирован. Обратитесь в поддержку",
+	m.UserStatusBanned:    "Аккаунт заблокирован",
+}
+
+// Проверяет, можно ли перевести аккаунт из состояния from в состояние to.
+func userStatusCanTransition(from, to string) bool {
+	return slices.Contains(userStatusTransitions[from], to)
+}
+
+// Проверяет, что аккаунт активен. Для неактивного аккаунта возвращает ошибку с понятным клиенту сообщением.
+func userCheckStatus(status string) *m.Err {
+	if status == m.UserStatusActive {
+		return nil
+	}
+
+	clientMsg, ok := userStatusClientMsgs[status]
+	if !ok {
+		clientMsg = userStatusClientMsgs[m.UserStatusBanned]
+	}
+	return &m.Err{
+		Code:      fasthttp.StatusForbidden,
+		ClientMsg: clientMsg,
+		Error:     fmt.Errorf("user status is %s", status),
+	}
+}
+
+// Проверяет, что аккаунт пользователя не удален и активен. Состояние берется из кэша statusCache,
+// поэтому блокировка вступает в силу для уже выданных access-токенов с задержкой до logic.status_cache.
+func (l *Logic) userCheckActive(userId int) *m.Err {
+	entry, ok := l.statusCache.get(userId)
+	if !ok {
+		userDb, exists, err := l.storage.User.GetById(userId)
+		if err != nil {
+			return &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+
+		entry = userStatusEntry{exists: exists}
+		if exists {
+			entry.status, entry.deleted = userDb.Status, userDb.DeletedAt != nil
+		}
+		l.statusCache.set(userId, entry)
+	}
+
+	if !entry.exists || entry.deleted {
+		return &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("user not found or deleted"),
+		}
+	}
+	return userCheckStatus(entry.status)
+}
+
+type userStatusEntry struct {
+	exists  bool
+	deleted bool
+	status  string
+}
+
+// Время жизни записей кэшей состояния аккаунтов и сессий (logic.status_cache).
+func statusCacheTtl(seconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = defaultStatusCache
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,144 @@
+package logic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/lesienchik/vk__test/internal/config"
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+)
+
+// Значения по умолчанию для claims jwt (если не заданы в конфиге).
+const (
+	defaultJwtIssuer    = "vktest"
+	defaultJwtAudience  = "vktest"
+	defaultJwtClockSkew = 30 // сек
+)
+
+// Заполняет незаданные параметры jwt значениями по умолчанию.
+func jwtWithDefaults(cfg config.Jwt) config.Jwt {
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultJwtIssuer
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = defaultJwtAudience
+	}
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = defaultJwtClockSkew
+	}
+	return cfg
+}
+
+// Генерирует access-токен пользователя в рамках сессии sessionId. В токен попадают роли и права пользователя,
+// поэтому их изменение вступает в силу с выдачей следующего access-токена.
+func (l *Logic) userGenAccessToken(userId, sessionId int) (string, error) {
+	jti, err := hashes.GenRandomToken(jtiSize)
+	if err != nil {
+		return "", fmt.Errorf("logic.userGenAccessToken(1): %w", err)
+	}
+
+	userRoles, err := l.storage.Role.GetByUserId(userId)
+	if err != nil {
+		return "", fmt.Errorf("logic.userGenAccessToken(2): %w", err)
+	}
+
+	claims := m.UserAuthClaims{
+		Id:               userId,
+		SessionId:        sessionId,
+		Type:             jwtTypeAccess,
+		Roles:            userRoles.Roles,
+		Scope:            strings.Join(userRoles.Permissions, " "),
+		RegisteredClaims: l.userRegisteredClaims(userId, jti, time.Now().Add(jwtExpiresAccessTime)),
+	}
+	return hashes.JwtGenToken(claims, l.keyRing)
+}
+
+// Генерирует refresh-токен пользователя с идентификатором jti.
+func (l *Logic) userGenRefreshToken(userId int, jti string, expiresAt time.Time) (string, error) {
+	claims := m.UserAuthClaims{
+		Id:               userId,
+		Type:             jwtTypeRefresh,
+		RegisteredClaims: l.userRegisteredClaims(userId, jti, expiresAt),
+	}
+	return hashes.JwtGenToken(claims, l.keyRing)
+}
+
+func (l *Logic) userRegisteredClaims(userId int, jti string, expiresAt time.Time) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    l.jwt.Issuer,
+		Subject:   strconv.Itoa(userId),
+		Audience:  jwt.ClaimStrings{l.jwt.Audience},
+		ID:        jti,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+}
+
+// Проверяет jwt пользователя: подпись, iss, aud, сроки (с допустимым расхождением часов) и тип токена typ.
+// Для истекшего, но в остальном корректного токена возвращает claims вместе со статусом hashes.JwtTokenExpires.
+// В переходном режиме (jwt.legacy_claims) принимаются и токены, выпущенные до появления typ, iss и aud: их тип
+// определяется по составу claims. Refresh-токен одноразовый (см. sessionRotate), поэтому старый refresh-токен
+// принимается один раз и заменяется токеном с новыми claims.
+func (l *Logic) userParseToken(token, typ string) (*m.UserAuthClaims, byte, error) {
+	leeway := jwt.WithLeeway(time.Duration(l.jwt.ClockSkew) * time.Second)
+
+	claims := new(m.UserAuthClaims)
+	_, status, err := hashes.JwtParseAndValidateToken(token, claims, l.keyRing,
+		jwt.WithIssuer(l.jwt.Issuer),
+		jwt.WithAudience(l.jwt.Audience),
+		leeway,
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	legacy := false
+	if err != nil && status != hashes.JwtTokenExpires && l.jwt.LegacyClaims {
+		legacyClaims := new(m.UserAuthClaims)
+		_, legacyStatus, legacyErr := hashes.JwtParseAndValidateToken(token, legacyClaims, l.keyRing,
+			leeway,
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		)
+		if legacyType := userLegacyTokenType(legacyClaims); legacyType != "" && (legacyErr == nil || legacyStatus == hashes.JwtTokenExpires) {
+			legacyClaims.Type = legacyType
+			claims, status, err, legacy = legacyClaims, legacyStatus, legacyErr, true
+		}
+	}
+	if err != nil && status != hashes.JwtTokenExpires {
+		return nil, status, fmt.Errorf("logic.userParseToken(1): %w", err)
+	}
+
+	if claims.Type != typ {
+		return nil, hashes.JwtTokenError, fmt.Errorf("logic.userParseToken(2): unexpected token type %q", claims.Type)
+	}
+	if !legacy && claims.Subject != strconv.Itoa(claims.Id) {
+		return nil, hashes.JwtTokenError, fmt.Errorf("logic.userParseToken(3): subject does not match user id")
+	}
+	if err != nil {
+		return claims, status, fmt.Errorf("logic.userParseToken(4): %w", err)
+	}
+	return claims, status, nil
+}
+
+// Определяет тип токена, выпущенного до появления typ, iss, aud и sub: в access-токене был только id сессии,
+// а в refresh-токене - только jti. Для токенов другого состава возвращает пустую строку.
+func userLegacyTokenType(claims *m.UserAuthClaims) string {
+	if claims.Type != "" || claims.Issuer != "" || len(claims.Audience) != 0 || claims.Subject != "" || claims.IssuedAt == nil {
+		return ""
+	}
+
+	switch {
+	case claims.SessionId != 0 && claims.ID == "":
+		return jwtTypeAccess
+	case claims.SessionId == 0 && claims.ID != "":
+		return jwtTypeRefresh
+	}
+	return ""
+}
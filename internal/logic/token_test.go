@@ -0,0 +1,63 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+func TestUserLegacyTokenType(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	issuedAt := jwt.NewNumericDate(time.Now())
+
+	testTable := []struct {
+		desc     string            // Описание теста
+		input    *m.UserAuthClaims // Входные данные
+		expected string            // Ожидаемый результат выполнения теста
+	}{
+		{
+			desc:     "Legacy access",
+			input:    &m.UserAuthClaims{Id: 1, SessionId: 2, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: issuedAt}},
+			expected: jwtTypeAccess,
+		},
+		{
+			desc:     "Legacy refresh",
+			input:    &m.UserAuthClaims{Id: 1, RegisteredClaims: jwt.RegisteredClaims{ID: "jti", IssuedAt: issuedAt}},
+			expected: jwtTypeRefresh,
+		},
+		{
+			desc:     "Current token",
+			input:    &m.UserAuthClaims{Id: 1, Type: jwtTypeRefresh, RegisteredClaims: jwt.RegisteredClaims{ID: "jti", IssuedAt: issuedAt}},
+			expected: "",
+		},
+		{
+			desc:     "Has issuer",
+			input:    &m.UserAuthClaims{Id: 1, RegisteredClaims: jwt.RegisteredClaims{Issuer: "vktest", ID: "jti", IssuedAt: issuedAt}},
+			expected: "",
+		},
+		{
+			desc:     "Both session id and jti",
+			input:    &m.UserAuthClaims{Id: 1, SessionId: 2, RegisteredClaims: jwt.RegisteredClaims{ID: "jti", IssuedAt: issuedAt}},
+			expected: "",
+		},
+		{
+			desc:     "No issued at",
+			input:    &m.UserAuthClaims{Id: 1, RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}},
+			expected: "",
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d (%s)", number, testCase.desc)
+
+		actual := userLegacyTokenType(testCase.input)
+		// Assert
+		requires.Equal(testCase.expected, actual)
+	}
+}
@@ -0,0 +1,351 @@
+package logic
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+	"github.com/lesienchik/vk__test/pkg/hashes"
+	"github.com/lesienchik/vk__test/pkg/totp"
+)
+
+// Начинает подключение 2FA: генерирует новый секрет и otpauth:// ссылку.
+// До подтверждения кодом (UserConfirm2fa) секрет не используется при входе.
+func (l *Logic) UserEnroll2fa(userId int) (*m.User2faEnrollResp, *m.Err) {
+	userDb, exists, err := l.storage.User.GetById(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	userTotp, exists, err := l.storage.Totp.GetByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if exists && userTotp.Enabled {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Двухфакторная аутентификация уже подключена",
+			Error:     errors.New("2fa already enabled"),
+		}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if err := l.storage.Totp.Upsert(userId, secret); err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	return &m.User2faEnrollResp{
+		Secret: secret,
+		Uri:    totp.URI(totpIssuer, userDb.Email, secret),
+	}, nil
+}
+
+// Подтверждает подключение 2FA кодом из приложения и возвращает одноразовые коды восстановления.
+func (l *Logic) UserConfirm2fa(userId int, code string) (*m.User2faRecoveryCodesResp, *m.Err) {
+	userTotp, exists, err := l.storage.Totp.GetByUserId(userId)
+	if err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Сначала начните подключение двухфакторной аутентификации",
+			Error:     errors.New("2fa enrolment not found"),
+		}
+	}
+	if userTotp.Enabled {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Двухфакторная аутентификация уже подключена",
+			Error:     errors.New("2fa already enabled"),
+		}
+	}
+
+	step, ok := totp.Validate(userTotp.Secret, code, time.Now())
+	if !ok {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный код",
+			Error:     errors.New("invalid totp code"),
+		}
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	codeHashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := genRecoveryCode()
+		if err != nil {
+			return nil, &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, hashes.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := l.storage.Totp.Enable(userId, codeHashes); err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if _, err := l.storage.Totp.UpdateLastUsedStep(userId, step); err != nil {
+		return nil, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	return &m.User2faRecoveryCodesResp{Codes: codes}, nil
+}
+
+// Отключает 2FA. Требует пароль и действующий TOTP-код (или код восстановления).
+func (l *Logic) UserDisable2fa(userReq *m.User2faDisableReq) *m.Err {
+	userDb, exists, err := l.storage.User.GetById(userReq.Id)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	if _, err := l.hasher.Verify(userDb.Password, userReq.Password); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный пароль",
+			Error:     err,
+		}
+	}
+
+	userTotp, exists, err := l.storage.Totp.GetByUserId(userDb.Id)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists || !userTotp.Enabled {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Двухфакторная аутентификация не подключена",
+			Error:     errors.New("2fa is not enabled"),
+		}
+	}
+
+	if errs := l.twoFactorCheckCode(userTotp, userReq.Code); errs != nil {
+		return errs
+	}
+
+	if err := l.storage.Totp.Delete(userDb.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	return nil
+}
+
+// Второй шаг аутентификации: обменивает challenge-токен и TOTP-код (или код восстановления) на id пользователя.
+func (l *Logic) UserAuth2fa(userReq *m.User2faAuthReq, client *m.ClientInfo) (int, *m.Err) {
+	challenge := new(m.User2faChallenge)
+	hashingStatus, err := hashes.HmacParseAndValidateHash(userReq.Challenge, challenge, l.keyRing)
+	if err != nil {
+		if hashingStatus == hashes.HashExpires {
+			return -1, &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Время на ввод кода истекло, войдите заново",
+				Error:     err,
+			}
+		}
+
+		return -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: err,
+		}
+	}
+	if challenge.Id <= 0 || challenge.Purpose != codePurpose2fa {
+		return -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("invalid challenge token"),
+		}
+	}
+
+	userTotp, exists, err := l.storage.Totp.GetByUserId(challenge.Id)
+	if err != nil {
+		return -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists || !userTotp.Enabled {
+		return -1, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("2fa is not enabled"),
+		}
+	}
+
+	// Перебор кодов ограничивается так же, как перебор паролей.
+	lockoutKeys := l.lockout2faKeys(challenge.Id, client.Ip)
+	if errs := l.lockoutCheck(lockoutKeys); errs != nil {
+		l.auditLoginFailure(challenge.Id, client, "", "2fa_locked_out")
+		return -1, errs
+	}
+	if errs := l.twoFactorCheckCode(userTotp, userReq.Code); errs != nil {
+		if errs.Code == fasthttp.StatusBadRequest {
+			l.lockoutFail(lockoutKeys, client.Ip)
+			l.auditLoginFailure(challenge.Id, client, "", "invalid_2fa_code")
+		} else {
+			l.lockoutRelease(lockoutKeys)
+		}
+		return -1, errs
+	}
+	l.lockoutReset(lockoutKeys)
+	l.auditRecord(m.AuditEventLoginSuccess, challenge.Id, challenge.Id, client, map[string]any{"method": "password+2fa"})
+	l.deviceCheckNew(challenge.Id, client)
+	return challenge.Id, nil
+}
+
+// Возвращает challenge-токен, если у пользователя включена 2FA, иначе пустую строку.
+func (l *Logic) twoFactorChallenge(userId int) (string, *m.Err) {
+	userTotp, exists, err := l.storage.Totp.GetByUserId(userId)
+	if err != nil {
+		return "", &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists || !userTotp.Enabled {
+		return "", nil
+	}
+
+	challenge, err := hashes.HmacGenHash(m.User2faChallenge{
+		Id:      userId,
+		Purpose: codePurpose2fa,
+	}, challengeExpiresTime, l.keyRing)
+	if err != nil {
+		return "", &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	return challenge, nil
+}
+
+// Проверяет TOTP-код (каждый код принимается только один раз) или одноразовый код восстановления.
+func (l *Logic) twoFactorCheckCode(userTotp *m.UserTotp, code string) *m.Err {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(userTotp.Secret, code, time.Now())
+		if !ok {
+			return &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Неверный код",
+				Error:     errors.New("invalid totp code"),
+			}
+		}
+
+		accepted, err := l.storage.Totp.UpdateLastUsedStep(userTotp.UserId, step)
+		if err != nil {
+			return &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+		if !accepted {
+			return &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Этот код уже был использован, дождитесь следующего",
+				Error:     errors.New("totp code has already been used"),
+			}
+		}
+		return nil
+	}
+
+	used, err := l.storage.Totp.UseRecoveryCode(userTotp.UserId, hashes.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !used {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный код",
+			Error:     errors.New("invalid recovery code"),
+		}
+	}
+	return nil
+}
+
+// Генерирует код восстановления вида "abcd-efgh".
+func genRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("logic.genRecoveryCode(1): %w", err)
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))
+	return code[:len(code)/2] + "-" + code[len(code)/2:], nil
+}
+
+// Приводит введенный код восстановления к виду, в котором хранится его хэш.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/valyala/fasthttp"
 
 	m "github.com/lesienchik/vk__test/internal/models"
@@ -14,7 +13,7 @@ import (
 )
 
 // Проводит валидацию полей пользователя при регистрации и проверяет на существование.
-func (l *Logic) UserRegister(userReq *m.UserRegReq) *m.Err {
+func (l *Logic) UserRegister(userReq *m.UserRegReq, client *m.ClientInfo) *m.Err {
 	// Валидация полей: username,email,password.
 	if !validator.IsValidUsername(userReq.Username) {
 		return &m.Err{
@@ -69,7 +68,8 @@ func (l *Logic) UserRegister(userReq *m.UserRegReq) *m.Err {
 		}
 	}
 
-	verifyCode, err := hashes.HmacGenHash(userReq, hashes.ExpiresDefault, l.secret)
+	// Пароль хранится только на стороне сервера (в виде хэша), а в письмо уходит случайный непрозрачный код.
+	hashPassword, err := l.hasher.Hash(userReq.Password)
 	if err != nil {
 		return &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
@@ -78,6 +78,32 @@ func (l *Logic) UserRegister(userReq *m.UserRegReq) *m.Err {
 		}
 	}
 
+	verifyCode, err := hashes.GenRandomToken(verifyCodeSize)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	err = l.storage.PendingRegistration.Create(&m.PendingRegistration{
+		TokenHash: hashes.HashToken(verifyCode),
+		Username:  userReq.Username,
+		Email:     userReq.Email,
+		Password:  hashPassword,
+		ExpiresAt: time.Now().Add(registrationExpiresTime),
+	})
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	l.auditRecord(m.AuditEventRegister, 0, 0, client, map[string]any{"email_hmac": l.auditEmailHmac(userReq.Email)})
+
 	// Не дожидаемся ответа об отправке сообщения на почту (дабы не заставлять пользователя ждать).
 	go func() {
 		if err := l.email.SendConfirmCode(userReq.Email, verifyCode); err != nil {
@@ -88,35 +114,34 @@ func (l *Logic) UserRegister(userReq *m.UserRegReq) *m.Err {
 	return nil
 }
 
-func (l *Logic) UserConfirm(verifyCode string) (int, *m.Err) {
-	userReq := new(m.UserRegReq)
-	hashingStatus, err := hashes.HmacParseAndValidateHash(verifyCode, userReq, l.secret)
+// Завершает регистрацию: переносит ожидающую подтверждения запись в таблицу пользователей.
+func (l *Logic) UserConfirm(verifyCode string, client *m.ClientInfo) (int, *m.Err) {
+	tokenHash := hashes.HashToken(verifyCode)
+	reg, exists, err := l.storage.PendingRegistration.GetByTokenHash(tokenHash)
 	if err != nil {
-		if hashingStatus == hashes.HashExpires {
-			return -1, &m.Err{
-				Code:      fasthttp.StatusBadRequest,
-				ClientMsg: "Срок действия кода подтверждения истек",
-				Error:     err,
-			}
+		return -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
 		}
-
+	}
+	if !exists {
 		return -1, &m.Err{
 			Code:      fasthttp.StatusBadRequest,
 			ClientMsg: "Неверный код подтверждения",
-			Error:     err,
+			Error:     errors.New("pending registration not found"),
 		}
 	}
-
-	if userReq.Username == "" || userReq.Email == "" || userReq.Password == "" {
+	if time.Now().After(reg.ExpiresAt) {
 		return -1, &m.Err{
-			Code:      fasthttp.StatusInternalServerError,
-			ClientMsg: msgInternalServerError,
-			Error:     errors.New("empty fields for userReq"),
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Срок действия кода подтверждения истек",
+			Error:     errors.New("pending registration has expired"),
 		}
 	}
 
 	// Проверяем пользователя на существование (по почте).
-	_, exists, err := l.storage.User.GetByEmail(userReq.Email)
+	_, exists, err = l.storage.User.GetByEmail(reg.Email)
 	if err != nil {
 		return -1, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
@@ -133,7 +158,7 @@ func (l *Logic) UserConfirm(verifyCode string) (int, *m.Err) {
 	}
 
 	// Проверяем пользователя на существование (по псевдониму).
-	_, exists, err = l.storage.User.GetByUsername(userReq.Username)
+	_, exists, err = l.storage.User.GetByUsername(reg.Username)
 	if err != nil {
 		return -1, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
@@ -149,60 +174,72 @@ func (l *Logic) UserConfirm(verifyCode string) (int, *m.Err) {
 		}
 	}
 
-	userId, errs := l.userCreate(userReq)
-	if errs != nil {
-		return -1, errs
+	// Удаление ожидающей записи и создание пользователя происходят атомарно, поэтому код одноразовый.
+	userId, confirmed, err := l.storage.PendingRegistration.Confirm(tokenHash)
+	if err != nil {
+		return -1, &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
 	}
-	return userId, nil
-}
+	if !confirmed {
+		// Регистрация осталась на месте: почту или псевдоним успела занять параллельная регистрация.
+		reg, exists, err := l.storage.PendingRegistration.GetByTokenHash(tokenHash)
+		if err != nil {
+			return -1, &m.Err{
+				Code:      fasthttp.StatusInternalServerError,
+				ClientMsg: msgInternalServerError,
+				Error:     err,
+			}
+		}
+		if exists && time.Now().Before(reg.ExpiresAt) {
+			return -1, &m.Err{
+				Code:      fasthttp.StatusConflict,
+				ClientMsg: "Пользователь с такой почтой или псевдонимом уже существует",
+				Error:     errors.New("email or username already exists"),
+			}
+		}
 
-// Сохраняет нового пользователя.
-func (l *Logic) userCreate(userReq *m.UserRegReq) (int, *m.Err) {
-	user := &m.User{
-		Username: userReq.Username,
-		Email:    userReq.Email,
+		return -1, &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный код подтверждения",
+			Error:     errors.New("pending registration has already been confirmed"),
+		}
 	}
+	l.auditRecord(m.AuditEventConfirm, userId, userId, client, nil)
+	return userId, nil
+}
 
-	hashPassword, err := hashes.HashPassword(userReq.Password)
+// Возвращает пару access,refresh токенов и заводит под refresh-токен новую сессию.
+func (l *Logic) UserSetJwtTokens(userId int, client *m.ClientInfo) ([]string, *m.Err) {
+	jti, err := hashes.GenRandomToken(jtiSize)
 	if err != nil {
-		return -1, &m.Err{
+		return nil, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
 			ClientMsg: msgInternalServerError,
 			Error:     err,
 		}
 	}
-	user.Password = string(hashPassword)
+	expiresAt := time.Now().Add(jwtExpiresRefreshTime)
 
-	userId, err := l.storage.User.Create(user)
+	sessionId, err := l.storage.Session.Create(&m.Session{
+		UserId:    userId,
+		Jti:       jti,
+		UserAgent: client.UserAgent,
+		Ip:        client.Ip,
+		ExpiresAt: expiresAt,
+	})
 	if err != nil {
-		return -1, &m.Err{
+		return nil, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
 			ClientMsg: msgInternalServerError,
 			Error:     err,
 		}
 	}
-	return userId, nil
-}
-
-// Возвращает пару access,refresh токенов.
-func (l *Logic) UserSetJwtTokens(userId int) ([]string, *m.Err) {
-	accessClaims := m.UserAuthClaims{
-		Id: userId,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtExpiresAccessTime)),
-		},
-	}
-	refreshClaims := m.UserAuthClaims{
-		Id: userId,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtExpiresRefreshTime)),
-		},
-	}
 
 	// Генерируем токены.
-	access, err := hashes.JwtGenToken(accessClaims, l.secret)
+	access, err := l.userGenAccessToken(userId, sessionId)
 	if err != nil {
 		return nil, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
@@ -210,7 +247,7 @@ func (l *Logic) UserSetJwtTokens(userId int) ([]string, *m.Err) {
 			Error:     err,
 		}
 	}
-	refresh, err := hashes.JwtGenToken(refreshClaims, l.secret)
+	refresh, err := l.userGenRefreshToken(userId, jti, expiresAt)
 	if err != nil {
 		return nil, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
@@ -221,102 +258,418 @@ func (l *Logic) UserSetJwtTokens(userId int) ([]string, *m.Err) {
 	return []string{access, refresh}, nil
 }
 
-func (l *Logic) UserAuth(userReq *m.UserAuthReq) (int, *m.Err) {
+// Аутентифицирует пользователя по почте и паролю. Если у пользователя включена 2FA, токены не выдаются:
+// вместо этого возвращается challenge-токен для второго шага (UserAuth2fa).
+// Неудачные попытки учитываются по аккаунту и по ip клиента (см. lockout.go).
+func (l *Logic) UserAuth(userReq *m.UserAuthReq, client *m.ClientInfo) (int, string, *m.Err) {
+	lockoutKeys := l.lockoutAuthKeys(userReq.Email, client.Ip)
+	if errs := l.lockoutCheck(lockoutKeys); errs != nil {
+		l.auditLoginFailure(0, client, userReq.Email, "locked_out")
+		return -1, "", errs
+	}
+
 	// Проверяем пользователя на существование (по почте).
 	userDb, exists, err := l.storage.User.GetByEmail(userReq.Email)
 	if err != nil {
-		return -1, &m.Err{
+		l.lockoutRelease(lockoutKeys)
+		return -1, "", &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
 			ClientMsg: msgInternalServerError,
 			Error:     err,
 		}
 	}
 	if !exists {
-		return -1, &m.Err{
+		l.lockoutFail(lockoutKeys, client.Ip)
+		l.auditLoginFailure(0, client, userReq.Email, "unknown_email")
+		return -1, "", &m.Err{
 			Code:      fasthttp.StatusBadRequest,
 			ClientMsg: "Неверная почта или пароль",
 			Error:     errors.New("invalid email or password"),
 		}
 	}
 
-	if err := hashes.CompareHashAndPassword(userDb.Password, userReq.Password); err != nil {
-		return -1, &m.Err{
+	rehash, err := l.hasher.Verify(userDb.Password, userReq.Password)
+	if err != nil {
+		l.lockoutFail(lockoutKeys, client.Ip)
+		l.auditLoginFailure(userDb.Id, client, userReq.Email, "invalid_password")
+		return -1, "", &m.Err{
 			Code:      fasthttp.StatusBadRequest,
 			ClientMsg: "Неверная почта или пароль",
 			Error:     err,
 		}
 	}
-	return userDb.Id, nil
+	l.lockoutReset(lockoutKeys)
+
+	// Сообщаем об удалении только после проверки пароля, чтобы не раскрывать состояние чужого аккаунта.
+	if userDb.DeletedAt != nil {
+		l.auditLoginFailure(userDb.Id, client, userReq.Email, "deleted")
+		return -1, "", &m.Err{
+			Code:      fasthttp.StatusForbidden,
+			ClientMsg: "Аккаунт удален. Восстановить его можно по ссылке из письма",
+			Error:     errors.New("user deleted"),
+		}
+	}
+	if errs := userCheckStatus(userDb.Status); errs != nil {
+		l.auditLoginFailure(userDb.Id, client, userReq.Email, userDb.Status)
+		return -1, "", errs
+	}
+
+	// Хэш создан устаревшим алгоритмом, с прежними параметрами или перцем: пересчитываем, пока известен пароль.
+	if rehash {
+		l.userRehashPassword(userDb, userReq.Password)
+	}
+
+	challenge, errs := l.twoFactorChallenge(userDb.Id)
+	if errs != nil {
+		return -1, "", errs
+	}
+
+	// С включенной 2FA вход завершится только после ввода кода (UserAuth2fa).
+	if challenge == "" {
+		l.auditRecord(m.AuditEventLoginSuccess, userDb.Id, userDb.Id, client, map[string]any{"method": "password"})
+		l.deviceCheckNew(userDb.Id, client)
+	}
+	return userDb.Id, challenge, nil
 }
 
-func (l *Logic) UserVerify(token string) (int, *m.Err) {
-	claims, _, err := hashes.JwtParseAndValidateToken(token, &m.UserAuthClaims{}, l.secret)
+// Пересчитывает хэш пароля текущим алгоритмом. Ошибка не мешает входу: хэш пересчитается при следующем.
+// Хэш заменяется, только если пароль не сменили параллельно с входом.
+func (l *Logic) userRehashPassword(userDb *m.User, password string) {
+	hashPassword, err := l.hasher.Hash(password)
 	if err != nil {
-		return -1, &m.Err{
+		l.logger.Error(fmt.Errorf("logic.userRehashPassword(1): %w", err))
+		return
+	}
+
+	updated, err := l.storage.User.RehashPasswordById(userDb.Id, userDb.Password, hashPassword)
+	if err != nil {
+		l.logger.Error(fmt.Errorf("logic.userRehashPassword(2): %w", err))
+		return
+	}
+	if !updated {
+		l.logger.Debugf("logic.userRehashPassword: password of user %d changed concurrently, rehash skipped", userDb.Id)
+	}
+}
+
+// Проверяет access-токен и возвращает его claims. Токены удаленных пользователей отклоняются.
+func (l *Logic) UserVerify(token string) (*m.UserAuthClaims, *m.Err) {
+	claims, _, err := l.userParseToken(token, jwtTypeAccess)
+	if err != nil {
+		return nil, &m.Err{
 			Code:  fasthttp.StatusBadRequest,
-			Error: errors.New("invalid access token"),
+			Error: fmt.Errorf("invalid access token: %w", err),
 		}
 	}
 
-	// Приведение claims к типу UserAuthClaims.
-	userAuthClaims, ok := claims.(*m.UserAuthClaims)
-	if !ok {
-		return -1, &m.Err{
+	// Заблокированный или удаленный пользователь, как и завершенная сессия, теряет доступ,
+	// не дожидаясь истечения access-токена.
+	if errs := l.userCheckActive(claims.Id); errs != nil {
+		return nil, errs
+	}
+	if errs := l.sessionCheckActive(claims.Id, claims.SessionId); errs != nil {
+		return nil, errs
+	}
+	return claims, nil
+}
+
+// Выдает новую пару access,refresh токенов по refresh-токену. Id пользователя берется из refresh-токена.
+// В строгом режиме дополнительно требуется истекший access-токен того же пользователя.
+func (l *Logic) UserRefresh(access, refresh string, client *m.ClientInfo) ([]string, *m.Err) {
+	userRefreshClaims, _, err := l.userParseToken(refresh, jwtTypeRefresh)
+	if err != nil {
+		return nil, &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: fmt.Errorf("invalid refresh token: %w", err),
+		}
+	}
+
+	if l.strictRefresh {
+		if errs := l.userCheckExpiredAccess(access, userRefreshClaims.Id); errs != nil {
+			return nil, errs
+		}
+	}
+
+	// Refresh-токены, выпущенные до смены пароля, считаются недействительными.
+	if errs := l.userCheckRefreshIssued(userRefreshClaims); errs != nil {
+		return nil, errs
+	}
+
+	// Ротация: старый refresh-токен выводится из оборота, взамен выдается новый из того же семейства.
+	newRefresh, sessionId, errs := l.sessionRotate(userRefreshClaims)
+	if errs != nil {
+		return nil, errs
+	}
+
+	newAccess, err := l.userGenAccessToken(userRefreshClaims.Id, sessionId)
+	if err != nil {
+		return nil, &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
 			ClientMsg: msgInternalServerError,
-			Error:     errors.New("failed to convert claims to the UserAuthClaims type"),
+			Error:     err,
 		}
 	}
-	return userAuthClaims.Id, nil
+	l.auditRecord(m.AuditEventRefresh, userRefreshClaims.Id, userRefreshClaims.Id, client, map[string]any{"session_id": sessionId})
+	return []string{newAccess, newRefresh}, nil
 }
 
-func (l *Logic) UserRefresh(access, refresh string) (string, *m.Err) {
-	accessClaims, tokenStatus, err := hashes.JwtParseAndValidateToken(access, &m.UserAuthClaims{}, l.secret)
-	if err != nil {
-		return "", &m.Err{
+// Проверяет, что access-токен принадлежит пользователю и уже истек (строгий режим обновления токенов).
+func (l *Logic) userCheckExpiredAccess(access string, userId int) *m.Err {
+	if access == "" {
+		return &m.Err{
+			Code:  fasthttp.StatusUnauthorized,
+			Error: errors.New("empty access token"),
+		}
+	}
+
+	// Для истекшего токена подпись уже проверена, а claims заполнены.
+	accessClaims, tokenStatus, err := l.userParseToken(access, jwtTypeAccess)
+	if err != nil && tokenStatus != hashes.JwtTokenExpires {
+		return &m.Err{
 			Code:  fasthttp.StatusBadRequest,
-			Error: errors.New("invalid access token"),
+			Error: fmt.Errorf("invalid access token: %w", err),
 		}
 	}
 	if tokenStatus == hashes.JwtTokenValid {
-		return "", &m.Err{
+		return &m.Err{
 			Code:  fasthttp.StatusBadRequest,
 			Error: errors.New("access token has not expired"),
 		}
 	}
 
-	if _, _, err = hashes.JwtParseAndValidateToken(refresh, &m.UserAuthClaims{}, l.secret); err != nil {
-		return "", &m.Err{
+	if accessClaims.Id != userId {
+		return &m.Err{
 			Code:  fasthttp.StatusBadRequest,
-			Error: errors.New("invalid refresh token"),
+			Error: errors.New("access and refresh tokens belong to different users"),
 		}
 	}
+	return nil
+}
 
-	// Приведение claims к типу UserAuthClaims.
-	userAuthClaims, ok := accessClaims.(*m.UserAuthClaims)
-	if !ok {
-		return "", &m.Err{
+// Проверяет, что refresh-токен был выпущен не раньше последней смены пароля пользователя.
+func (l *Logic) userCheckRefreshIssued(refreshClaims *m.UserAuthClaims) *m.Err {
+	userDb, exists, err := l.storage.User.GetById(refreshClaims.Id)
+	if err != nil {
+		return &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
 			ClientMsg: msgInternalServerError,
-			Error:     errors.New("failed to convert access claims to the UserAuthClaims type"),
+			Error:     err,
 		}
 	}
+	if !exists {
+		return &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("user not found"),
+		}
+	}
+	if userDb.DeletedAt != nil {
+		return &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("user deleted"),
+		}
+	}
+	if errs := userCheckStatus(userDb.Status); errs != nil {
+		return errs
+	}
+
+	// Время в jwt хранится с точностью до секунды.
+	if refreshClaims.IssuedAt == nil || refreshClaims.IssuedAt.Before(userDb.PasswordChangedAt.Truncate(time.Second)) {
+		return &m.Err{
+			Code:  fasthttp.StatusBadRequest,
+			Error: errors.New("refresh token was issued before the password change"),
+		}
+	}
+	return nil
+}
+
+// Меняет пароль аутентифицированного пользователя.
+func (l *Logic) UserChangePassword(userReq *m.UserChangePassReq, client *m.ClientInfo) *m.Err {
+	userDb, exists, err := l.storage.User.GetById(userReq.Id)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists {
+		return &m.Err{
+			Code:      fasthttp.StatusNotFound,
+			ClientMsg: "Пользователь не найден",
+			Error:     errors.New("user not found"),
+		}
+	}
+
+	if _, err := l.hasher.Verify(userDb.Password, userReq.OldPassword); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный текущий пароль",
+			Error:     err,
+		}
+	}
+	if !validator.IsValidPassword(userReq.NewPassword) {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Пароль не удовлетворяет требованиям",
+		}
+	}
+	if userReq.NewPassword == userReq.OldPassword {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Новый пароль совпадает с текущим",
+			Error:     errors.New("new password matches the old one"),
+		}
+	}
+
+	hashPassword, err := l.hasher.Hash(userReq.NewPassword)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+
+	// Вместе с паролем обновляется password_changed_at, что отзывает все ранее выданные refresh-токены.
+	if err := l.storage.User.UpdatePasswordById(userDb.Id, hashPassword); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if err := l.storage.Session.RevokeAllByUserId(userDb.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.auditRecord(m.AuditEventPasswordChange, userDb.Id, userDb.Id, client, nil)
+	return nil
+}
+
+// Отправляет на почту пользователя ссылку для сброса пароля.
+// Ответ не зависит от того, существует ли пользователь с такой почтой, чтобы по нему нельзя было перебирать аккаунты.
+func (l *Logic) UserResetPassword(userReq *m.UserResetPassReq, client *m.ClientInfo) *m.Err {
+	if !validator.IsValidEmail(userReq.Email) {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверный email",
+		}
+	}
+
+	userDb, exists, err := l.storage.User.GetByEmail(userReq.Email)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	if !exists || userDb.DeletedAt != nil {
+		l.logger.Debugf("logic.UserResetPassword: user with email %s not found", userReq.Email)
+		l.auditRecord(m.AuditEventPasswordResetRequest, 0, 0, client, map[string]any{"email_hmac": l.auditEmailHmac(userReq.Email), "user_found": false})
+		return nil
+	}
+	l.auditRecord(m.AuditEventPasswordResetRequest, 0, userDb.Id, client, map[string]any{"user_found": true})
+
+	if err := l.userSendResetPassCode(userDb); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	return nil
+}
+
+// Отправляет пользователю письмо со ссылкой для сброса пароля.
+func (l *Logic) userSendResetPassCode(userDb *m.User) error {
+	// Код привязан ко времени последней смены пароля: после его использования он перестает быть действительным.
+	resetCode, err := hashes.HmacGenHash(m.UserResetPassCode{
+		Id:        userDb.Id,
+		Purpose:   codePurposeResetPass,
+		ChangedAt: userDb.PasswordChangedAt.UnixMicro(),
+	}, hashes.ExpiresTenMinute, l.keyRing)
+	if err != nil {
+		return fmt.Errorf("logic.userSendResetPassCode(1): %w", err)
+	}
+
+	go func() {
+		if err := l.email.SendResetPassCode(userDb.Email, resetCode); err != nil {
+			l.logger.Error(fmt.Errorf("logic.userSendResetPassCode(2): %w", err))
+			return
+		}
+	}()
+	return nil
+}
+
+// Устанавливает новый пароль пользователя по коду из письма.
+func (l *Logic) UserConfirmResetPassword(userReq *m.UserConfirmResetPassReq, client *m.ClientInfo) *m.Err {
+	resetCode := new(m.UserResetPassCode)
+	hashingStatus, err := hashes.HmacParseAndValidateHash(userReq.Code, resetCode, l.keyRing)
+	if err != nil {
+		if hashingStatus == hashes.HashExpires {
+			return &m.Err{
+				Code:      fasthttp.StatusBadRequest,
+				ClientMsg: "Срок действия ссылки для сброса пароля истек",
+				Error:     err,
+			}
+		}
 
-	claims := m.UserAuthClaims{
-		Id: userAuthClaims.Id,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtExpiresAccessTime)),
-		},
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для сброса пароля",
+			Error:     err,
+		}
+	}
+	if resetCode.Id <= 0 || resetCode.Purpose != codePurposeResetPass {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Неверная ссылка для сброса пароля",
+			Error:     errors.New("empty user id in reset code"),
+		}
+	}
+
+	if !validator.IsValidPassword(userReq.NewPassword) {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Пароль не удовлетворяет требованиям",
+		}
+	}
+
+	hashPassword, err := l.hasher.Hash(userReq.NewPassword)
+	if err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
 	}
 
-	token, err := hashes.JwtGenToken(claims, l.secret)
+	// Пароль обновится, только если его не меняли после выдачи кода (в том числе по этому же коду).
+	updated, err := l.storage.User.ResetPasswordById(resetCode.Id, hashPassword, time.UnixMicro(resetCode.ChangedAt))
 	if err != nil {
-		return "", &m.Err{
+		return &m.Err{
 			Code:      fasthttp.StatusInternalServerError,
 			ClientMsg: msgInternalServerError,
 			Error:     err,
 		}
 	}
-	return token, nil
+	if !updated {
+		return &m.Err{
+			Code:      fasthttp.StatusBadRequest,
+			ClientMsg: "Ссылка для сброса пароля уже была использована",
+			Error:     errors.New("reset code has already been used"),
+		}
+	}
+	if err := l.storage.Session.RevokeAllByUserId(resetCode.Id); err != nil {
+		return &m.Err{
+			Code:      fasthttp.StatusInternalServerError,
+			ClientMsg: msgInternalServerError,
+			Error:     err,
+		}
+	}
+	l.auditRecord(m.AuditEventPasswordReset, resetCode.Id, resetCode.Id, client, nil)
+	return nil
 }
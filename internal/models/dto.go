@@ -1,6 +1,12 @@
 package models
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 /*
 Здесь описываются структуры, которые используются для обработки и передачи данных.
@@ -18,10 +24,19 @@ type UserAuthReq struct { // При аутентификации пользов
 }
 
 type UserAuthClaims struct { // Для создания jwt-токенов аутентифицированного пользователя.
-	Id int `json:"id"`
+	Id        int      `json:"id"`
+	SessionId int      `json:"sid,omitempty"`   // Id сессии (только в access-токене)
+	Type      string   `json:"typ"`             // Тип токена: access или refresh
+	Roles     []string `json:"roles,omitempty"` // Роли пользователя (только в access-токене)
+	Scope     string   `json:"scope,omitempty"` // Права пользователя через пробел (только в access-токене)
 	jwt.RegisteredClaims
 }
 
+// Проверяет, есть ли у пользователя право permission.
+func (c *UserAuthClaims) HasPermission(permission string) bool {
+	return slices.Contains(strings.Fields(c.Scope), permission)
+}
+
 type UserAccessResp struct { // Для отдачи access-токена в теле ответа.
 	Token string `json:"access_token"`
 }
@@ -32,12 +47,211 @@ type UserChangePassReq struct { // При смене пароля пользов
 	NewPassword string `json:"new_password"`
 }
 
-type UserResetPassReq struct {
+type UserResetPassReq struct { // При запросе на сброс пароля.
 	Id    int    `json:"-"`
 	Email string `json:"email"`
 }
 
-type UserConfirmResetPassReq struct {
+type UserMagicLinkReq struct { // При запросе ссылки для входа без пароля.
+	Email string `json:"email"`
+}
+
+type UserMagicLinkConfirmReq struct { // При входе по ссылке из письма.
+	Code string `json:"code"`
+}
+
+type UserMagicLinkCode struct { // Содержимое кода ссылки для входа без пароля.
+	Id      int    `json:"id"`
+	Purpose string `json:"purpose"`
+	Jti     string `json:"jti"` // Идентификатор кода, делает его одноразовым (см. storage.ConsumedToken)
+}
+
+type UserConfirmResetPassReq struct { // При подтверждении сброса пароля.
 	Email       string `json:"-"`
+	Code        string `json:"code"`
 	NewPassword string `json:"new_password"`
 }
+
+type UserResetPassCode struct { // Содержимое кода для сброса пароля.
+	Id        int    `json:"id"`
+	Purpose   string `json:"purpose"`
+	ChangedAt int64  `json:"changed_at"` // Время последней смены пароля (в микросекундах), делает код одноразовым
+}
+
+type UserSessionResp struct { // Для отдачи списка активных сессий (устройств) пользователя.
+	Id         int       `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	Ip         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // Сессия, с которой пришел запрос
+}
+
+type User2faEnrollResp struct { // Для отдачи нового TOTP-секрета при подключении 2FA.
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"` // otpauth:// ссылка (для QR-кода)
+}
+
+type User2faCodeReq struct { // При подтверждении подключения 2FA.
+	Code string `json:"code"`
+}
+
+type User2faRecoveryCodesResp struct { // Для отдачи одноразовых кодов восстановления (показываются один раз).
+	Codes []string `json:"recovery_codes"`
+}
+
+type User2faDisableReq struct { // При отключении 2FA.
+	Id       int    `json:"-"`
+	Password string `json:"password"`
+	Code     string `json:"code"` // TOTP-код или код восстановления
+}
+
+type User2faAuthReq struct { // При втором шаге аутентификации.
+	Challenge string `json:"challenge_token"`
+	Code      string `json:"code"` // TOTP-код или код восстановления
+}
+
+type User2faChallengeResp struct { // Для отдачи challenge-токена, если у пользователя включена 2FA.
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Challenge         string `json:"challenge_token"`
+}
+
+type User2faChallenge struct { // Содержимое challenge-токена второго шага аутентификации.
+	Id      int    `json:"id"`
+	Purpose string `json:"purpose"`
+}
+
+type UserProfileResp struct { // Для отдачи профиля текущего пользователя.
+	Id               int       `json:"id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email"`
+	CreatedAt        time.Time `json:"created_at"`
+	EmailVerified    bool      `json:"email_verified"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+}
+
+type UserUpdateReq struct { // При изменении профиля (меняются только переданные поля).
+	Id       int     `json:"-"`
+	Username *string `json:"username"`
+}
+
+type UserChangeEmailReq struct { // При запросе на смену почты.
+	Id       int    `json:"-"`
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+type UserChangeEmailCode struct { // Содержимое кода подтверждения (и отмены) смены почты.
+	Id        int    `json:"id"`
+	Purpose   string `json:"purpose"`
+	NewEmail  string `json:"new_email"`
+	CreatedAt int64  `json:"created_at"` // Время создания запроса (в микросекундах), привязывает код к запросу
+}
+
+type UserDeleteReq struct { // При удалении аккаунта.
+	Id       int    `json:"-"`
+	Password string `json:"password"`
+}
+
+type UserDeleteResp struct { // Для отдачи времени окончательного удаления аккаунта.
+	PurgeAt time.Time `json:"purge_at"` // До этого момента аккаунт можно восстановить по ссылке из письма
+}
+
+type UserRestoreCode struct { // Содержимое кода восстановления удаленного аккаунта.
+	Id        int    `json:"id"`
+	Purpose   string `json:"purpose"`
+	DeletedAt int64  `json:"deleted_at"` // Время удаления (в микросекундах), делает код одноразовым
+}
+
+type UserRevokeSessionsReq struct { // При завершении всех сессий по ссылке из уведомления о входе с нового устройства.
+	Code string `json:"code"`
+}
+
+type UserRevokeSessionsCode struct { // Содержимое кода из уведомления о входе с нового устройства ("это был не я").
+	Id      int    `json:"id"`
+	Purpose string `json:"purpose"`
+	Jti     string `json:"jti"` // Идентификатор кода, делает его одноразовым (см. storage.ConsumedToken)
+}
+
+type UserExportResp struct { // Архив всех данных, которые хранятся о пользователе.
+	ExportedAt    time.Time                `json:"exported_at"`
+	Profile       *UserProfileResp         `json:"profile"`
+	Sessions      []*UserExportSessionResp `json:"sessions"`       // Все сессии, включая завершенные (история входов)
+	LoginLockouts []*UserExportLockoutResp `json:"login_lockouts"` // Блокировки входа после неудачных попыток
+	KnownDevices  []*UserExportDeviceResp  `json:"known_devices"`  // Устройства, с которых пользователь входил
+	AuditEvents   []*AuditEventResp        `json:"audit_events"`   // Журнал событий безопасности аккаунта
+}
+
+type UserExportSessionResp struct {
+	Id         int        `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	Ip         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type UserExportDeviceResp struct {
+	UserAgent   string    `json:"user_agent"`
+	Ip          string    `json:"ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+type UserExportLockoutResp struct {
+	Ip          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AdminUserResp struct { // Для отдачи пользователя администратору.
+	Id              int        `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerified   bool       `json:"email_verified"`
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+type AdminUserDetailResp struct { // Для отдачи администратору подробностей об одном пользователе.
+	AdminUserResp
+	Roles            []string `json:"roles"`
+	TwoFactorEnabled bool     `json:"two_factor_enabled"`
+	ActiveSessions   int      `json:"active_sessions"`
+}
+
+type AdminUsersResp struct { // Для отдачи страницы списка пользователей.
+	Users  []*AdminUserResp `json:"users"`
+	Total  int              `json:"total"` // Всего пользователей, подходящих под фильтр
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+type AdminUserActionReq struct { // При действии администратора над пользователем.
+	AdminId int    `json:"-"`
+	UserId  int    `json:"-"`
+	Reason  string `json:"reason"` // Причина (обязательна для блокировки)
+}
+
+type AuditEventResp struct { // Для отдачи события журнала безопасности.
+	Id        int64          `json:"id"`
+	Type      string         `json:"type"`
+	ActorId   int            `json:"actor_id,omitempty"` // Кто совершил действие
+	UserId    int            `json:"user_id,omitempty"`  // Чей аккаунт затронут
+	Ip        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	RequestId string         `json:"request_id"`
+	Metadata  map[string]any `json:"metadata"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type AuditEventsResp struct { // Для отдачи страницы журнала (от новых событий к старым).
+	Events     []*AuditEventResp `json:"events"`
+	NextBefore int64             `json:"next_before,omitempty"` // Значение before для следующей страницы (нет - страница последняя)
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 /*
 Здесь находятся общие структуры, которые обеспечивают единый формат передачи данных и могут применяться
 в разных частях приложения.
@@ -26,15 +28,166 @@ type RespErrMsg struct {
 	Detail string `json:"detail,omitempty"` // Ошибка для разработчика
 }
 
+// Клиентское сообщение для непредвиденных (внутренних) ошибок.
+const MsgInternalServerError = "Упс! Что-то пошло не так..."
+
 type Err struct { // Внутренняя структура ошибок.
-	Code      int
-	ClientMsg string
-	Error     error
+	Code       int
+	ClientMsg  string
+	Error      error
+	RetryAfter int // Через сколько секунд можно повторить запрос (заголовок Retry-After)
 }
 
 type User struct { // Общая структура пользователя.
-	Id       int
-	Username string
-	Email    string
-	Password string
+	Id                int
+	Username          string
+	Email             string
+	Password          string
+	PasswordChangedAt time.Time // Время последней смены пароля
+	EmailVerified     bool      // Почта подтверждена
+	CreatedAt         time.Time
+	DeletedAt         *time.Time // Время удаления аккаунта (nil - аккаунт не удален)
+	Status            string     // Состояние аккаунта (UserStatus*)
+	StatusReason      string     // Причина блокировки
+	StatusChangedAt   *time.Time
+}
+
+// Состояния аккаунта пользователя.
+const (
+	UserStatusPending   = "pending" // Создан, но еще не активирован
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended" // Временно заблокирован администратором
+	UserStatusBanned    = "banned"    // Заблокирован администратором
+)
+
+type UserFilter struct { // Условия выборки пользователей (пустые поля не учитываются).
+	Query   string // Подстрока псевдонима или почты
+	Status  string
+	Role    string
+	Deleted *bool // true - только удаленные, false - только неудаленные
+	Limit   int
+	Offset  int
+}
+
+// Роли, создаваемые миграциями.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user" // Назначается каждому новому пользователю
+)
+
+// Права доступа (вид "<ресурс>:<действие>"), создаваемые миграциями.
+const (
+	PermUsersRead  = "users:read"
+	PermUsersWrite = "users:write"
+	PermAuditRead  = "audit:read"
+)
+
+type UserRoles struct { // Роли пользователя и права, которые они дают.
+	Roles       []string
+	Permissions []string
+}
+
+type PendingRegistration struct { // Регистрация, ожидающая подтверждения почты.
+	TokenHash string // sha256 от кода подтверждения
+	Username  string
+	Email     string
+	Password  string // Хэш пароля
+	ExpiresAt time.Time
+}
+
+type EmailChange struct { // Запрос на смену почты, ожидающий подтверждения нового адреса.
+	UserId    int
+	OldEmail  string
+	NewEmail  string
+	ExpiresAt time.Time
+}
+
+type Session struct { // Сессия пользователя (одна на каждый выданный refresh-токен).
+	Id         int
+	UserId     int
+	Jti        string // Идентификатор refresh-токена
+	UserAgent  string
+	Ip         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time // nil, если сессия не отозвана
+}
+
+type KnownDevice struct { // Устройство (сочетание user agent и ip), с которого пользователь уже входил.
+	Id          int64
+	UserId      int
+	UserAgent   string
+	Ip          string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+type ClientInfo struct { // Сведения о клиенте, от которого пришел запрос.
+	UserAgent string
+	Ip        string
+	RequestId string
+}
+
+// Типы событий журнала безопасности.
+const (
+	AuditEventRegister             = "user.register"               // Запрос на регистрацию (письмо с подтверждением отправлено)
+	AuditEventConfirm              = "user.confirm"                // Почта подтверждена, пользователь создан
+	AuditEventLoginSuccess         = "user.login.success"          // Успешный вход
+	AuditEventLoginFailure         = "user.login.failure"          // Неудачная попытка входа (причина в metadata.reason)
+	AuditEventNewDevice            = "user.login.new_device"       // Вход с нового устройства (отправлено уведомление)
+	AuditEventMagicLinkRequest     = "user.magic_link.request"     // Запрос ссылки для входа без пароля
+	AuditEventRefresh              = "user.refresh"                // Обновление пары токенов
+	AuditEventPasswordChange       = "user.password.change"        // Смена пароля пользователем
+	AuditEventPasswordResetRequest = "user.password.reset_request" // Запрос ссылки для сброса пароля
+	AuditEventPasswordReset        = "user.password.reset"         // Пароль сброшен по ссылке из письма
+	AuditEventSessionRevoke        = "user.session.revoke"         // Завершение сессии (одной или всех)
+
+	AuditEventAdminSuspend        = "admin.user.suspend"
+	AuditEventAdminBan            = "admin.user.ban"
+	AuditEventAdminUnban          = "admin.user.unban"
+	AuditEventAdminPasswordReset  = "admin.user.password_reset"
+	AuditEventAdminRevokeSessions = "admin.user.sessions_revoke"
+	AuditEventAdminDelete         = "admin.user.delete"
+)
+
+type AuditEvent struct { // Событие журнала безопасности. Нулевой ActorId или UserId означает, что они неизвестны.
+	Id        int64
+	Type      string
+	ActorId   int // Кто совершил действие
+	UserId    int // Чей аккаунт затронут
+	Ip        string
+	UserAgent string
+	RequestId string
+	Metadata  map[string]any
+	CreatedAt time.Time
+}
+
+type AuditFilter struct { // Условия выборки событий журнала (пустые поля не учитываются).
+	UserId int
+	Type   string
+	Before int64 // Только события с id меньше заданного (следующая страница)
+	Limit  int
+}
+
+type UserTotp struct { // Настройки двухфакторной аутентификации (TOTP) пользователя.
+	UserId       int
+	Secret       string
+	Enabled      bool  // false, пока пользователь не подтвердил привязку кодом
+	LastUsedStep int64 // Последний принятый временной шаг
+}
+
+type LoginAttempt struct { // Счетчик неудачных попыток входа (по аккаунту или по ip).
+	Key          string
+	Failures     int
+	FirstFailAt  time.Time // Начало текущего окна подсчета
+	BlockedUntil time.Time // До этого момента попытки входа отклоняются
+}
+
+type LoginLockout struct { // Запись о временной блокировке входа.
+	Key         string
+	Ip          string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
 }
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// Журнал событий безопасности. Только добавление: события не изменяются и не удаляются
+// (кроме обезличивания при окончательном удалении аккаунта, см. User.DeleteMarkedBefore).
+type Audit interface {
+	// Create info
+	Create(event *m.AuditEvent) error
+
+	// Get info
+	GetPage(filter *m.AuditFilter) ([]*m.AuditEvent, error)
+}
+
+type audit struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewAudit(logger *logrus.Logger, db *sql.DB) *audit {
+	return &audit{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (a *audit) Create(event *m.AuditEvent) error {
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	rawMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("storage.Audit.Create(1): %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_events (type, actor_id, user_id, ip, user_agent, request_id, metadata)
+		VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err = a.db.QueryRow(query, event.Type, event.ActorId, event.UserId, event.Ip, event.UserAgent, event.RequestId, rawMetadata).
+		Scan(&event.Id, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("storage.Audit.Create(2): %w", err)
+	}
+	return nil
+}
+
+// Возвращает страницу событий, подходящих под фильтр (от новых к старым).
+func (a *audit) GetPage(filter *m.AuditFilter) ([]*m.AuditEvent, error) {
+	query := `
+		SELECT
+			id,
+			type,
+			COALESCE(actor_id, 0),
+			COALESCE(user_id, 0),
+			ip,
+			user_agent,
+			request_id,
+			metadata,
+			created_at
+		FROM audit_events
+		WHERE ($1 = 0 OR user_id = $1)
+		AND ($2 = '' OR type = $2)
+		AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+
+	rows, err := a.db.Query(query, filter.UserId, filter.Type, filter.Before, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage.Audit.GetPage(1): %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*m.AuditEvent, 0)
+	for rows.Next() {
+		event := new(m.AuditEvent)
+		var rawMetadata []byte
+		if err := rows.Scan(&event.Id, &event.Type, &event.ActorId, &event.UserId, &event.Ip, &event.UserAgent,
+			&event.RequestId, &rawMetadata, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("storage.Audit.GetPage(2): %w", err)
+		}
+		if err := json.Unmarshal(rawMetadata, &event.Metadata); err != nil {
+			return nil, fmt.Errorf("storage.Audit.GetPage(3): %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.Audit.GetPage(4): %w", err)
+	}
+	return events, nil
+}
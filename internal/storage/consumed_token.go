@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Хранилище использованных одноразовых кодов. Сами коды подписаны (HmacGenHash) и в базе не хранятся:
+// запоминается только их jti, чтобы код нельзя было использовать повторно.
+type ConsumedToken interface {
+	// Create info
+	Consume(jti, purpose string, expiresAt time.Time) (bool, error)
+
+	// Delete info
+	DeleteExpired() (int64, error)
+}
+
+type consumedToken struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewConsumedToken(logger *logrus.Logger, db *sql.DB) *consumedToken {
+	return &consumedToken{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Отмечает код как использованный. Возвращает false, если код с таким jti уже был использован.
+func (c *consumedToken) Consume(jti, purpose string, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO consumed_tokens (jti, purpose, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	res, err := c.db.Exec(query, jti, purpose, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("storage.ConsumedToken.Consume(1): %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.ConsumedToken.Consume(2): %w", err)
+	}
+	return rows == 1, nil
+}
+
+// Удаляет записи об истекших кодах: повторно использовать их уже нельзя из-за срока действия.
+func (c *consumedToken) DeleteExpired() (int64, error) {
+	res, err := c.db.Exec(`DELETE FROM consumed_tokens WHERE expires_at < now()`)
+	if err != nil {
+		return 0, fmt.Errorf("storage.ConsumedToken.DeleteExpired(1): %w", err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("storage.ConsumedToken.DeleteExpired(2): %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type EmailChange interface {
+	// Create info
+	Create(change *m.EmailChange) (time.Time, error)
+
+	// Confirm info
+	Confirm(userId int, newEmail string, createdAt time.Time) (bool, error)
+
+	// Delete info
+	Cancel(userId int, newEmail string, createdAt time.Time) (bool, error)
+}
+
+type emailChange struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewEmailChange(logger *logrus.Logger, db *sql.DB) *emailChange {
+	return &emailChange{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Сохраняет запрос на смену почты и возвращает время его создания. Предыдущий незавершенный запрос
+// пользователя заменяется. Время создания входит в коды из писем, поэтому ссылки прежнего запроса
+// перестают работать, даже если новый запрос сделан на тот же адрес.
+func (e *emailChange) Create(change *m.EmailChange) (time.Time, error) {
+	query := `
+		INSERT INTO email_changes (user_id, old_email, new_email, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET old_email = EXCLUDED.old_email, new_email = EXCLUDED.new_email, expires_at = EXCLUDED.expires_at, created_at = now()
+		RETURNING created_at
+	`
+
+	var createdAt time.Time
+	if err := e.db.QueryRow(query, change.UserId, change.OldEmail, change.NewEmail, change.ExpiresAt).Scan(&createdAt); err != nil {
+		return time.Time{}, fmt.Errorf("storage.EmailChange.Create(1): %w", err)
+	}
+	return createdAt, nil
+}
+
+// Меняет почту пользователя на новую в одной транзакции с удалением запроса.
+// Возвращает false, если действующего запроса нет, почта пользователя уже изменилась или новый адрес занят.
+func (e *emailChange) Confirm(userId int, newEmail string, createdAt time.Time) (bool, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("storage.EmailChange.Confirm(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `
+		DELETE FROM email_changes
+		WHERE user_id = $1 AND new_email = $2 AND created_at = $3 AND expires_at > now()
+		RETURNING old_email
+	`
+
+	var oldEmail string
+	if err := tx.QueryRow(deleteQuery, userId, newEmail, createdAt).Scan(&oldEmail); err != nil {
+		if err != sql.ErrNoRows {
+			return false, fmt.Errorf("storage.EmailChange.Confirm(2): %w", err)
+		}
+		return false, nil
+	}
+
+	updateQuery := `
+		UPDATE users
+		SET email = $3
+		WHERE id = $1 AND email = $2
+	`
+
+	res, err := tx.Exec(updateQuery, userId, oldEmail, newEmail)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("storage.EmailChange.Confirm(3): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.EmailChange.Confirm(4): %w", err)
+	}
+	if affected != 1 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("storage.EmailChange.Confirm(5): %w", err)
+	}
+	return true, nil
+}
+
+// Отменяет запрос на смену почты. Возвращает false, если действующего запроса с таким адресом нет.
+func (e *emailChange) Cancel(userId int, newEmail string, createdAt time.Time) (bool, error) {
+	query := `
+		DELETE FROM email_changes
+		WHERE user_id = $1 AND new_email = $2 AND created_at = $3 AND expires_at > now()
+	`
+
+	res, err := e.db.Exec(query, userId, newEmail, createdAt)
+	if err != nil {
+		return false, fmt.Errorf("storage.EmailChange.Cancel(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.EmailChange.Cancel(2): %w", err)
+	}
+	return affected == 1, nil
+}
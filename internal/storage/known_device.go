@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type KnownDevice interface {
+	// Create info
+	Touch(device *m.KnownDevice) (bool, error)
+
+	// Get info
+	CountByUserId(userId int) (int, error)
+	GetAllByUserId(userId int) ([]*m.KnownDevice, error)
+}
+
+type knownDevice struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewKnownDevice(logger *logrus.Logger, db *sql.DB) *knownDevice {
+	return &knownDevice{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Запоминает устройство пользователя или обновляет время последнего входа с него.
+// Возвращает true, если устройство раньше не встречалось.
+func (k *knownDevice) Touch(device *m.KnownDevice) (bool, error) {
+	insertQuery := `
+		INSERT INTO known_devices (user_id, user_agent, ip)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, ip, md5(user_agent)) DO NOTHING
+	`
+
+	res, err := k.db.Exec(insertQuery, device.UserId, device.UserAgent, device.Ip)
+	if err != nil {
+		return false, fmt.Errorf("storage.KnownDevice.Touch(1): %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.KnownDevice.Touch(2): %w", err)
+	}
+	if rows == 1 {
+		return true, nil
+	}
+
+	updateQuery := `
+		UPDATE known_devices
+		SET last_seen_at = now()
+		WHERE user_id = $1 AND ip = $2 AND md5(user_agent) = md5($3)
+	`
+
+	if _, err := k.db.Exec(updateQuery, device.UserId, device.Ip, device.UserAgent); err != nil {
+		return false, fmt.Errorf("storage.KnownDevice.Touch(3): %w", err)
+	}
+	return false, nil
+}
+
+func (k *knownDevice) CountByUserId(userId int) (int, error) {
+	query := `SELECT count(*) FROM known_devices WHERE user_id = $1`
+
+	var count int
+	if err := k.db.QueryRow(query, userId).Scan(&count); err != nil {
+		return -1, fmt.Errorf("storage.KnownDevice.CountByUserId(1): %w", err)
+	}
+	return count, nil
+}
+
+func (k *knownDevice) GetAllByUserId(userId int) ([]*m.KnownDevice, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			user_agent,
+			ip,
+			first_seen_at,
+			last_seen_at
+		FROM known_devices
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := k.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("storage.KnownDevice.GetAllByUserId(1): %w", err)
+	}
+	defer rows.Close()
+
+	devices := make([]*m.KnownDevice, 0)
+	for rows.Next() {
+		device := new(m.KnownDevice)
+		if err := rows.Scan(
+			&device.Id,
+			&device.UserId,
+			&device.UserAgent,
+			&device.Ip,
+			&device.FirstSeenAt,
+			&device.LastSeenAt,
+		); err != nil {
+			return nil, fmt.Errorf("storage.KnownDevice.GetAllByUserId(2): %w", err)
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.KnownDevice.GetAllByUserId(3): %w", err)
+	}
+	return devices, nil
+}
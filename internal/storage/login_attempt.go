@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+// Хранилище счетчиков неудачных попыток входа. Есть две реализации: в памяти процесса
+// (подходит для одной реплики) и в Postgres (общая для всех реплик).
+type LoginAttempt interface {
+	// Update info
+	Fail(key string, window time.Duration) (*m.LoginAttempt, error)
+	Release(key string) error
+	Block(key string, until time.Time) error
+	Lock(key string, until time.Time) error
+
+	// Delete info
+	Reset(key string) error
+}
+
+// Реализация в Postgres.
+type loginAttemptPostgres struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewLoginAttemptPostgres(logger *logrus.Logger, db *sql.DB) *loginAttemptPostgres {
+	return &loginAttemptPostgres{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Атомарно увеличивает счетчик неудач и возвращает его новое значение. Если окно подсчета истекло, счет начинается заново.
+func (l *loginAttemptPostgres) Fail(key string, window time.Duration) (*m.LoginAttempt, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, first_fail_at)
+		VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.first_fail_at < now() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			first_fail_at = CASE
+				WHEN login_attempts.first_fail_at < now() - make_interval(secs => $2) THEN now()
+				ELSE login_attempts.first_fail_at
+			END
+		RETURNING key, failures, first_fail_at, blocked_until
+	`
+
+	var attempt m.LoginAttempt
+	if err := l.db.QueryRow(query, key, window.Seconds()).Scan(&attempt.Key, &attempt.Failures, &attempt.FirstFailAt, &attempt.BlockedUntil); err != nil {
+		return nil, fmt.Errorf("storage.LoginAttempt.Fail(1): %w", err)
+	}
+	return &attempt, nil
+}
+
+// Отменяет одно увеличение счетчика (попытка не состоялась или оказалась успешной).
+func (l *loginAttemptPostgres) Release(key string) error {
+	query := `
+		UPDATE login_attempts
+		SET failures = GREATEST(failures - 1, 0)
+		WHERE key = $1
+	`
+
+	if _, err := l.db.Exec(query, key); err != nil {
+		return fmt.Errorf("storage.LoginAttempt.Release(1): %w", err)
+	}
+	return nil
+}
+
+// Задерживает следующие попытки до until. Уже действующую более долгую задержку не сокращает.
+func (l *loginAttemptPostgres) Block(key string, until time.Time) error {
+	query := `
+		UPDATE login_attempts
+		SET blocked_until = GREATEST(blocked_until, $2)
+		WHERE key = $1
+	`
+
+	if _, err := l.db.Exec(query, key, until); err != nil {
+		return fmt.Errorf("storage.LoginAttempt.Block(1): %w", err)
+	}
+	return nil
+}
+
+// Блокирует попытки до until. Подсчет неудач начнется заново после окончания блокировки.
+func (l *loginAttemptPostgres) Lock(key string, until time.Time) error {
+	query := `
+		UPDATE login_attempts
+		SET blocked_until = GREATEST(blocked_until, $2), failures = 0, first_fail_at = $2
+		WHERE key = $1
+	`
+
+	if _, err := l.db.Exec(query, key, until); err != nil {
+		return fmt.Errorf("storage.LoginAttempt.Lock(1): %w", err)
+	}
+	return nil
+}
+
+func (l *loginAttemptPostgres) Reset(key string) error {
+	if _, err := l.db.Exec(`DELETE FROM login_attempts WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("storage.LoginAttempt.Reset(1): %w", err)
+	}
+	return nil
+}
+
+// Реализация в памяти процесса.
+type loginAttemptMemory struct {
+	mu       sync.Mutex
+	attempts map[string]*m.LoginAttempt
+}
+
+func NewLoginAttemptMemory() *loginAttemptMemory {
+	return &loginAttemptMemory{
+		attempts: make(map[string]*m.LoginAttempt),
+	}
+}
+
+func (l *loginAttemptMemory) Fail(key string, window time.Duration) (*m.LoginAttempt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	attempt, ok := l.attempts[key]
+	if !ok {
+		attempt = &m.LoginAttempt{Key: key}
+		l.attempts[key] = attempt
+	}
+
+	if attempt.Failures == 0 || attempt.FirstFailAt.Before(now.Add(-window)) {
+		attempt.Failures = 0
+		attempt.FirstFailAt = now
+	}
+	attempt.Failures++
+
+	// Попутно удаляем устаревшие счетчики, чтобы карта не росла бесконечно.
+	for k, a := range l.attempts {
+		if a.FirstFailAt.Before(now.Add(-window)) && a.BlockedUntil.Before(now) {
+			delete(l.attempts, k)
+		}
+	}
+
+	res := *attempt
+	return &res, nil
+}
+
+func (l *loginAttemptMemory) Release(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if attempt, ok := l.attempts[key]; ok && attempt.Failures > 0 {
+		attempt.Failures--
+	}
+	return nil
+}
+
+func (l *loginAttemptMemory) Block(key string, until time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if attempt, ok := l.attempts[key]; ok && until.After(attempt.BlockedUntil) {
+		attempt.BlockedUntil = until
+	}
+	return nil
+}
+
+func (l *loginAttemptMemory) Lock(key string, until time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if attempt, ok := l.attempts[key]; ok {
+		if until.After(attempt.BlockedUntil) {
+			attempt.BlockedUntil = until
+		}
+		attempt.Failures = 0
+		attempt.FirstFailAt = until
+	}
+	return nil
+}
+
+func (l *loginAttemptMemory) Reset(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+	return nil
+}
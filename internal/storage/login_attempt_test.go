@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginAttemptMemoryFail(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	window := 20 * time.Millisecond
+
+	testTable := []struct {
+		desc     string        // Описание теста
+		failures int           // Неудач до проверяемой
+		release  bool          // Снять одну неудачу перед проверяемой
+		reset    bool          // Сбросить счетчик перед проверяемой
+		lock     bool          // Заблокировать перед проверяемой (счет начнется заново после блокировки)
+		wait     time.Duration // Пауза перед проверяемой неудачей
+		expected int           // Ожидаемый результат выполнения теста (значение счетчика)
+	}{
+		{
+			desc:     "First failure",
+			expected: 1,
+		},
+		{
+			desc:     "Counts within window",
+			failures: 2,
+			expected: 3,
+		},
+		{
+			desc:     "Starts over after window",
+			failures: 2,
+			wait:     2 * window,
+			expected: 1,
+		},
+		{
+			desc:     "Release",
+			failures: 2,
+			release:  true,
+			expected: 2,
+		},
+		{
+			desc:     "Reset",
+			failures: 2,
+			reset:    true,
+			expected: 1,
+		},
+		{
+			desc:     "Lock",
+			failures: 2,
+			lock:     true,
+			expected: 1,
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d (%s)", number, testCase.desc)
+
+		store := NewLoginAttemptMemory()
+		for i := 0; i < testCase.failures; i++ {
+			_, err := store.Fail("account:user@mail.ru", window)
+			requires.NoError(err)
+		}
+		if testCase.release {
+			requires.NoError(store.Release("account:user@mail.ru"))
+		}
+		if testCase.reset {
+			requires.NoError(store.Reset("account:user@mail.ru"))
+		}
+		if testCase.lock {
+			requires.NoError(store.Lock("account:user@mail.ru", time.Now()))
+		}
+		time.Sleep(testCase.wait)
+
+		actual, err := store.Fail("account:user@mail.ru", window)
+		// Assert
+		requires.NoError(err)
+		requires.Equal(testCase.expected, actual.Failures)
+	}
+}
+
+func TestLoginAttemptMemoryBlock(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	store := NewLoginAttemptMemory()
+	_, err := store.Fail("ip:127.0.0.1", time.Minute)
+	requires.NoError(err)
+	longer := time.Now().Add(time.Hour)
+
+	// Act
+	requires.NoError(store.Block("ip:127.0.0.1", longer))
+	requires.NoError(store.Block("ip:127.0.0.1", time.Now().Add(time.Second)))
+	actual, err := store.Fail("ip:127.0.0.1", time.Minute)
+
+	// Assert
+	requires.NoError(err)
+	requires.True(actual.BlockedUntil.Equal(longer)) // Более короткая задержка не сокращает действующую
+	requires.Equal(2, actual.Failures)
+}
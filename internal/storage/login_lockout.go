@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type LoginLockout interface {
+	// Create info
+	Create(lockout *m.LoginLockout) error
+
+	// Get info
+	GetByKey(key string) ([]*m.LoginLockout, error)
+}
+
+type loginLockout struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewLoginLockout(logger *logrus.Logger, db *sql.DB) *loginLockout {
+	return &loginLockout{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (l *loginLockout) Create(lockout *m.LoginLockout) error {
+	query := `
+		INSERT INTO login_lockouts (key, ip, failures, locked_until)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := l.db.Exec(query, lockout.Key, lockout.Ip, lockout.Failures, lockout.LockedUntil); err != nil {
+		return fmt.Errorf("storage.LoginLockout.Create(1): %w", err)
+	}
+	return nil
+}
+
+func (l *loginLockout) GetByKey(key string) ([]*m.LoginLockout, error) {
+	query := `
+		SELECT
+			key,
+			ip,
+			failures,
+			locked_until,
+			created_at
+		FROM login_lockouts WHERE key = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := l.db.Query(query, key)
+	if err != nil {
+		return nil, fmt.Errorf("storage.LoginLockout.GetByKey(1): %w", err)
+	}
+	defer rows.Close()
+
+	lockouts := make([]*m.LoginLockout, 0)
+	for rows.Next() {
+		lockout := new(m.LoginLockout)
+		if err := rows.Scan(&lockout.Key, &lockout.Ip, &lockout.Failures, &lockout.LockedUntil, &lockout.CreatedAt); err != nil {
+			return nil, fmt.Errorf("storage.LoginLockout.GetByKey(2): %w", err)
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.LoginLockout.GetByKey(3): %w", err)
+	}
+	return lockouts, nil
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type PendingRegistration interface {
+	// Create info
+	Create(reg *m.PendingRegistration) error
+
+	// Get info
+	GetByTokenHash(tokenHash string) (*m.PendingRegistration, bool, error)
+
+	// Confirm info
+	Confirm(tokenHash string) (int, bool, error)
+}
+
+type pendingRegistration struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewPendingRegistration(logger *logrus.Logger, db *sql.DB) *pendingRegistration {
+	return &pendingRegistration{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Сохраняет регистрацию, ожидающую подтверждения. Заодно удаляет просроченные записи. Действующие регистрации
+// на ту же почту остаются: иначе любой мог бы отменить чужую регистрацию, указав ту же почту.
+func (p *pendingRegistration) Create(reg *m.PendingRegistration) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage.PendingRegistration.Create(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM pending_registrations WHERE expires_at < now()`); err != nil {
+		return fmt.Errorf("storage.PendingRegistration.Create(2): %w", err)
+	}
+
+	query := `
+		INSERT INTO pending_registrations (token_hash, username, email, password, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.Exec(query, reg.TokenHash, reg.Username, reg.Email, reg.Password, reg.ExpiresAt); err != nil {
+		return fmt.Errorf("storage.PendingRegistration.Create(3): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storage.PendingRegistration.Create(4): %w", err)
+	}
+	return nil
+}
+
+func (p *pendingRegistration) GetByTokenHash(tokenHash string) (*m.PendingRegistration, bool, error) {
+	query := `
+		SELECT
+			token_hash,
+			username,
+			email,
+			password,
+			expires_at
+		FROM pending_registrations WHERE token_hash = $1
+	`
+
+	var reg m.PendingRegistration
+	if err := p.db.QueryRow(query, tokenHash).Scan(&reg.TokenHash, &reg.Username, &reg.Email, &reg.Password, &reg.ExpiresAt); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, false, fmt.Errorf("storage.PendingRegistration.GetByTokenHash(1): %w", err)
+		}
+		return nil, false, nil
+	}
+	return &reg, true, nil
+}
+
+// Превращает ожидающую регистрацию в пользователя в одной транзакции.
+// Возвращает false, если действующей регистрации с таким кодом нет (например, код уже использован)
+// или почта либо псевдоним уже заняты (тогда регистрация остается).
+func (p *pendingRegistration) Confirm(tokenHash string) (int, bool, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return -1, false, fmt.Errorf("storage.PendingRegistration.Confirm(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `
+		DELETE FROM pending_registrations
+		WHERE token_hash = $1 AND expires_at > now()
+		RETURNING username, email, password
+	`
+
+	var reg m.PendingRegistration
+	if err := tx.QueryRow(deleteQuery, tokenHash).Scan(&reg.Username, &reg.Email, &reg.Password); err != nil {
+		if err != sql.ErrNoRows {
+			return -1, false, fmt.Errorf("storage.PendingRegistration.Confirm(2): %w", err)
+		}
+		return -1, false, nil
+	}
+
+	insertQuery := `
+		INSERT INTO users (username, email, password)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	var id int
+	if err := tx.QueryRow(insertQuery, reg.Username, reg.Email, reg.Password).Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			return -1, false, nil
+		}
+		return -1, false, fmt.Errorf("storage.PendingRegistration.Confirm(3): %w", err)
+	}
+
+	// Новый пользователь получает роль по умолчанию.
+	rolesQuery := `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE name = $2
+	`
+	if _, err := tx.Exec(rolesQuery, id, m.RoleUser); err != nil {
+		return -1, false, fmt.Errorf("storage.PendingRegistration.Confirm(4): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, false, fmt.Errorf("storage.PendingRegistration.Confirm(5): %w", err)
+	}
+	return id, true, nil
+}
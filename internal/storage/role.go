@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type Role interface {
+	// Get info
+	GetByUserId(userId int) (*m.UserRoles, error)
+}
+
+type role struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewRole(logger *logrus.Logger, db *sql.DB) *role {
+	return &role{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Возвращает роли пользователя и объединение прав этих ролей (без повторов, по алфавиту).
+func (r *role) GetByUserId(userId int) (*m.UserRoles, error) {
+	query := `
+		SELECT r.name, rp.permission
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		WHERE ur.user_id = $1
+		ORDER BY r.name, rp.permission
+	`
+
+	rows, err := r.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("storage.Role.GetByUserId(1): %w", err)
+	}
+	defer rows.Close()
+
+	userRoles := &m.UserRoles{Roles: make([]string, 0), Permissions: make([]string, 0)}
+	permissions := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var permission sql.NullString
+		if err := rows.Scan(&name, &permission); err != nil {
+			return nil, fmt.Errorf("storage.Role.GetByUserId(2): %w", err)
+		}
+
+		if n := len(userRoles.Roles); n == 0 || userRoles.Roles[n-1] != name {
+			userRoles.Roles = append(userRoles.Roles, name)
+		}
+		if permission.Valid && !permissions[permission.String] {
+			permissions[permission.String] = true
+			userRoles.Permissions = append(userRoles.Permissions, permission.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.Role.GetByUserId(3): %w", err)
+	}
+
+	sort.Strings(userRoles.Permissions)
+	return userRoles, nil
+}
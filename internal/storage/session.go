@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type Session interface {
+	// Create info
+	Create(session *m.Session) (int, error)
+
+	// Update info
+	Rotate(oldJti, newJti string) (bool, error)
+	RevokeById(id int) error
+	RevokeByUserIdAndId(userId, id int) (bool, error)
+	RevokeByJti(jti string) error
+	RevokeAllByUserId(userId int) error
+
+	// Get info
+	GetById(id int) (*m.Session, bool, error)
+	GetByJti(jti string) (*m.Session, bool, error)
+	GetByRetiredJti(jti string) (*m.Session, bool, error)
+	GetActiveByUserId(userId int) ([]*m.Session, error)
+	GetAllByUserId(userId int) ([]*m.Session, error)
+}
+
+type session struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewSession(logger *logrus.Logger, db *sql.DB) *session {
+	return &session{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (s *session) Create(session *m.Session) (int, error) {
+	query := `
+		INSERT INTO sessions (user_id, jti, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	var id int
+	if err := s.db.QueryRow(query, session.UserId, session.Jti, session.UserAgent, session.Ip, session.ExpiresAt).Scan(&id); err != nil {
+		return -1, fmt.Errorf("storage.Session.Create(1): %w", err)
+	}
+	return id, nil
+}
+
+// Колонки сессии для выборки (в порядке сканирования scanSession).
+const sessionColumns = `
+	s.id,
+	s.user_id,
+	s.jti,
+	s.user_agent,
+	s.ip,
+	s.created_at,
+	s.last_used_at,
+	s.expires_at,
+	s.revoked_at
+`
+
+func scanSession(row rowScanner, session *m.Session) error {
+	return row.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.Jti,
+		&session.UserAgent,
+		&session.Ip,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+}
+
+func (s *session) GetById(id int) (*m.Session, bool, error) {
+	query := `SELECT` + sessionColumns + `FROM sessions s WHERE s.id = $1`
+
+	var session m.Session
+	if err := scanSession(s.db.QueryRow(query, id), &session); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, false, fmt.Errorf("storage.Session.GetById(1): %w", err)
+		}
+		return nil, false, nil
+	}
+	return &session, true, nil
+}
+
+func (s *session) GetByJti(jti string) (*m.Session, bool, error) {
+	query := `SELECT` + sessionColumns + `FROM sessions s WHERE s.jti = $1`
+
+	var session m.Session
+	if err := scanSession(s.db.QueryRow(query, jti), &session); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, false, fmt.Errorf("storage.Session.GetByJti(1): %w", err)
+		}
+		return nil, false, nil
+	}
+	return &session, true, nil
+}
+
+// Ищет сессию, к семейству которой относится уже выведенный из оборота refresh-токен.
+func (s *session) GetByRetiredJti(jti string) (*m.Session, bool, error) {
+	query := `SELECT` + sessionColumns + `
+		FROM session_retired_tokens r
+		JOIN sessions s ON s.id = r.session_id
+		WHERE r.jti = $1
+	`
+
+	var session m.Session
+	if err := scanSession(s.db.QueryRow(query, jti), &session); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, false, fmt.Errorf("storage.Session.GetByRetiredJti(1): %w", err)
+		}
+		return nil, false, nil
+	}
+	return &session, true, nil
+}
+
+// Заменяет текущий refresh-токен сессии на новый, а старый выводит из оборота.
+// Возвращает false, если oldJti уже не является текущим токеном активной сессии.
+func (s *session) Rotate(oldJti, newJti string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("storage.Session.Rotate(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	updateQuery := `
+		UPDATE sessions
+		SET jti = $2, last_used_at = now()
+		WHERE jti = $1 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING id
+	`
+
+	var id int
+	if err := tx.QueryRow(updateQuery, oldJti, newJti).Scan(&id); err != nil {
+		if err != sql.ErrNoRows {
+			return false, fmt.Errorf("storage.Session.Rotate(2): %w", err)
+		}
+		return false, nil
+	}
+
+	insertQuery := `
+		INSERT INTO session_retired_tokens (jti, session_id)
+		VALUES ($1, $2)
+	`
+	if _, err := tx.Exec(insertQuery, oldJti, id); err != nil {
+		return false, fmt.Errorf("storage.Session.Rotate(3): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("storage.Session.Rotate(4): %w", err)
+	}
+	return true, nil
+}
+
+func (s *session) RevokeById(id int) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := s.db.Exec(query, id); err != nil {
+		return fmt.Errorf("storage.Session.RevokeById(1): %w", err)
+	}
+	return nil
+}
+
+func (s *session) RevokeByJti(jti string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE jti = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := s.db.Exec(query, jti); err != nil {
+		return fmt.Errorf("storage.Session.RevokeByJti(1): %w", err)
+	}
+	return nil
+}
+
+func (s *session) RevokeAllByUserId(userId int) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := s.db.Exec(query, userId); err != nil {
+		return fmt.Errorf("storage.Session.RevokeAllByUserId(1): %w", err)
+	}
+	return nil
+}
+
+// Отзывает сессию, только если она принадлежит пользователю. Возвращает false, если активной сессии нет.
+func (s *session) RevokeByUserIdAndId(userId, id int) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	res, err := s.db.Exec(query, id, userId)
+	if err != nil {
+		return false, fmt.Errorf("storage.Session.RevokeByUserIdAndId(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.Session.RevokeByUserIdAndId(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Возвращает неотозванные и неистекшие сессии пользователя, начиная с последней использованной.
+func (s *session) GetActiveByUserId(userId int) ([]*m.Session, error) {
+	query := `SELECT` + sessionColumns + `
+		FROM sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > now()
+		ORDER BY s.last_used_at DESC
+	`
+
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("storage.Session.GetActiveByUserId(1): %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*m.Session, 0)
+	for rows.Next() {
+		session := new(m.Session)
+		if err := scanSession(rows, session); err != nil {
+			return nil, fmt.Errorf("storage.Session.GetActiveByUserId(2): %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.Session.GetActiveByUserId(3): %w", err)
+	}
+	return sessions, nil
+}
+
+// Возвращает все сессии пользователя, включая завершенные и истекшие (история входов).
+func (s *session) GetAllByUserId(userId int) ([]*m.Session, error) {
+	query := `SELECT` + sessionColumns + `
+		FROM sessions s
+		WHERE s.user_id = $1
+		ORDER BY s.created_at DESC
+	`
+
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("storage.Session.GetAllByUserId(1): %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*m.Session, 0)
+	for rows.Next() {
+		session := new(m.Session)
+		if err := scanSession(rows, session); err != nil {
+			return nil, fmt.Errorf("storage.Session.GetAllByUserId(2): %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage.Session.GetAllByUserId(3): %w", err)
+	}
+	return sessions, nil
+}
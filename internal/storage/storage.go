@@ -2,16 +2,65 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/lesienchik/vk__test/internal/config"
 )
 
 type Storage struct {
-	User User
+	User                User
+	PendingRegistration PendingRegistration
+	EmailChange         EmailChange
+	Session             Session
+	KnownDevice         KnownDevice
+	ConsumedToken       ConsumedToken
+	Role                Role
+	Totp                Totp
+	LoginAttempt        LoginAttempt
+	LoginLockout        LoginLockout
+	Audit               Audit
 }
 
-func New(logger *logrus.Logger, db *sql.DB) *Storage {
+func New(logger *logrus.Logger, db *sql.DB, lockoutCfg *config.Lockout) *Storage {
+	var loginAttempt LoginAttempt = NewLoginAttemptPostgres(logger, db)
+	if lockoutCfg.Store == "memory" {
+		loginAttempt = NewLoginAttemptMemory()
+	}
+
 	return &Storage{
-		User: NewUser(logger, db),
+		User:                NewUser(logger, db),
+		PendingRegistration: NewPendingRegistration(logger, db),
+		EmailChange:         NewEmailChange(logger, db),
+		Session:             NewSession(logger, db),
+		KnownDevice:         NewKnownDevice(logger, db),
+		ConsumedToken:       NewConsumedToken(logger, db),
+		Role:                NewRole(logger, db),
+		Totp:                NewTotp(logger, db),
+		LoginAttempt:        loginAttempt,
+		LoginLockout:        NewLoginLockout(logger, db),
+		Audit:               NewAudit(logger, db),
 	}
 }
+
+// Общий интерфейс для *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// Проверяет, что ошибка - нарушение уникального ограничения в Postgres.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// Экранирует спецсимволы шаблона LIKE, чтобы строка искалась как есть.
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
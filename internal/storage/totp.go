@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	m "github.com/lesienchik/vk__test/internal/models"
+)
+
+type Totp interface {
+	// Create info
+	Upsert(userId int, secret string) error
+
+	// Update info
+	Enable(userId int, recoveryCodeHashes []string) error
+	UpdateLastUsedStep(userId int, step int64) (bool, error)
+	UseRecoveryCode(userId int, codeHash string) (bool, error)
+
+	// Get info
+	GetByUserId(userId int) (*m.UserTotp, bool, error)
+
+	// Delete info
+	Delete(userId int) error
+}
+
+type totp struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewTotp(logger *logrus.Logger, db *sql.DB) *totp {
+	return &totp{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Сохраняет новый (еще не подтвержденный) секрет. Уже включенную 2FA не перезаписывает.
+func (t *totp) Upsert(userId int, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+		WHERE user_totp.enabled = false
+	`
+
+	if _, err := t.db.Exec(query, userId, secret); err != nil {
+		return fmt.Errorf("storage.Totp.Upsert(1): %w", err)
+	}
+	return nil
+}
+
+func (t *totp) GetByUserId(userId int) (*m.UserTotp, bool, error) {
+	query := `
+		SELECT
+			user_id,
+			secret,
+			enabled,
+			last_used_step
+		FROM user_totp WHERE user_id = $1
+	`
+
+	var userTotp m.UserTotp
+	if err := t.db.QueryRow(query, userId).Scan(&userTotp.UserId, &userTotp.Secret, &userTotp.Enabled, &userTotp.LastUsedStep); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, false, fmt.Errorf("storage.Totp.GetByUserId(1): %w", err)
+		}
+		return nil, false, nil
+	}
+	return &userTotp, true, nil
+}
+
+// Включает 2FA и заменяет коды восстановления новыми.
+func (t *totp) Enable(userId int, recoveryCodeHashes []string) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage.Totp.Enable(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE user_totp SET enabled = true WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("storage.Totp.Enable(2): %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("storage.Totp.Enable(3): %w", err)
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userId, codeHash); err != nil {
+			return fmt.Errorf("storage.Totp.Enable(4): %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storage.Totp.Enable(5): %w", err)
+	}
+	return nil
+}
+
+// Запоминает принятый временной шаг. Возвращает false, если код этого (или более позднего) шага уже использовался.
+func (t *totp) UpdateLastUsedStep(userId int, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+
+	res, err := t.db.Exec(query, userId, step)
+	if err != nil {
+		return false, fmt.Errorf("storage.Totp.UpdateLastUsedStep(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.Totp.UpdateLastUsedStep(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Помечает код восстановления использованным. Возвращает false, если такого неиспользованного кода нет.
+func (t *totp) UseRecoveryCode(userId int, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	res, err := t.db.Exec(query, userId, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("storage.Totp.UseRecoveryCode(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.Totp.UseRecoveryCode(2): %w", err)
+	}
+	return affected >= 1, nil
+}
+
+// Отключает 2FA: удаляет секрет и коды восстановления.
+func (t *totp) Delete(userId int) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage.Totp.Delete(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("storage.Totp.Delete(2): %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("storage.Totp.Delete(3): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storage.Totp.Delete(4): %w", err)
+	}
+	return nil
+}
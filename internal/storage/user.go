@@ -3,6 +3,7 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -15,11 +16,22 @@ type User interface {
 
 	// Update info
 	UpdatePasswordById(id int, newPassword string) error
+	RehashPasswordById(id int, oldPassword, newPassword string) (bool, error)
+	ResetPasswordById(id int, newPassword string, changedAt time.Time) (bool, error)
+	InvalidatePasswordById(id int) (time.Time, bool, error)
+	UpdateUsernameById(id int, username string) (bool, error)
+	MarkDeletedById(id int) (bool, error)
+	RestoreById(id int, deletedAt time.Time) (bool, error)
+	UpdateStatusById(id int, from, to, reason string) (bool, error)
 
 	// Get info
 	GetById(userId int) (*m.User, bool, error)
 	GetByEmail(email string) (*m.User, bool, error)
 	GetByUsername(username string) (*m.User, bool, error)
+	GetList(filter *m.UserFilter) ([]*m.User, int, error)
+
+	// Delete info
+	DeleteMarkedBefore(before time.Time) (int64, error)
 }
 
 type user struct {
@@ -59,18 +71,42 @@ func (u *user) Create(user *m.User) (int, error) {
 	return id, nil
 }
 
+// Колонки пользователя для выборки (в порядке сканирования scanUser).
+const userColumns = `
+	id,
+	username,
+	email,
+	password,
+	password_changed_at,
+	email_verified,
+	created_at,
+	deleted_at,
+	status,
+	status_reason,
+	status_changed_at
+`
+
+func scanUser(row rowScanner, user *m.User) error {
+	return row.Scan(
+		&user.Id,
+		&user.Username,
+		&user.Email,
+		&user.Password,
+		&user.PasswordChangedAt,
+		&user.EmailVerified,
+		&user.CreatedAt,
+		&user.DeletedAt,
+		&user.Status,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+	)
+}
+
 func (u *user) GetById(userId int) (*m.User, bool, error) {
-	query := `
-		SELECT
-			id,
-			username,
-			email,
-			password
-		FROM users WHERE id = $1
-	`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
 	var user m.User
-	if err := u.db.QueryRow(query, userId).Scan(&user.Id, &user.Username, &user.Email, &user.Password); err != nil {
+	if err := scanUser(u.db.QueryRow(query, userId), &user); err != nil {
 		if err != sql.ErrNoRows {
 			return nil, false, fmt.Errorf("storage.User.GetById(1): %w", err)
 		}
@@ -80,17 +116,10 @@ func (u *user) GetById(userId int) (*m.User, bool, error) {
 }
 
 func (u *user) GetByEmail(email string) (*m.User, bool, error) {
-	query := `
-		SELECT
-			id,
-			username,
-			email,
-			password
-		FROM users WHERE email = $1
-	`
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
 
 	var user m.User
-	if err := u.db.QueryRow(query, email).Scan(&user.Id, &user.Username, &user.Email, &user.Password); err != nil {
+	if err := scanUser(u.db.QueryRow(query, email), &user); err != nil {
 		if err != sql.ErrNoRows {
 			return nil, false, fmt.Errorf("storage.User.GetByEmail(1): %w", err)
 		}
@@ -100,17 +129,10 @@ func (u *user) GetByEmail(email string) (*m.User, bool, error) {
 }
 
 func (u *user) GetByUsername(username string) (*m.User, bool, error) {
-	query := `
-		SELECT
-			id,
-			username,
-			email,
-			password
-		FROM users WHERE username = $1
-	`
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
 
 	var user m.User
-	if err := u.db.QueryRow(query, username).Scan(&user.Id, &user.Username, &user.Email, &user.Password); err != nil {
+	if err := scanUser(u.db.QueryRow(query, username), &user); err != nil {
 		if err != sql.ErrNoRows {
 			return nil, false, fmt.Errorf("storage.User.GetByUsername(1): %w", err)
 		}
@@ -119,10 +141,11 @@ func (u *user) GetByUsername(username string) (*m.User, bool, error) {
 	return &user, true, nil
 }
 
+// Обновляет хэш пароля вместе с password_changed_at (это отзывает refresh-токены и коды сброса).
 func (u *user) UpdatePasswordById(id int, newPassword string) error {
 	query := `
 		UPDATE users
-		SET password = $2
+		SET password = $2, password_changed_at = now()
 		WHERE id = $1
 	`
 
@@ -141,3 +164,230 @@ func (u *user) UpdatePasswordById(id int, newPassword string) error {
 	}
 	return nil
 }
+
+// Заменяет хэш того же пароля пересчитанным (password_changed_at не меняется), только если в базе все еще
+// хэш oldPassword. Возвращает false, если пароль успели сменить: иначе старый пароль вернулся бы поверх нового.
+func (u *user) RehashPasswordById(id int, oldPassword, newPassword string) (bool, error) {
+	query := `
+		UPDATE users
+		SET password = $3
+		WHERE id = $1 AND password = $2
+	`
+
+	res, err := u.db.Exec(query, id, oldPassword, newPassword)
+	if err != nil {
+		return false, fmt.Errorf("storage.User.RehashPasswordById(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.User.RehashPasswordById(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Обновляет пароль, только если он не менялся с момента changedAt. Возвращает false, если пароль уже был изменен.
+func (u *user) ResetPasswordById(id int, newPassword string, changedAt time.Time) (bool, error) {
+	query := `
+		UPDATE users
+		SET password = $2, password_changed_at = now()
+		WHERE id = $1 AND password_changed_at = $3
+	`
+
+	res, err := u.db.Exec(query, id, newPassword, changedAt)
+	if err != nil {
+		return false, fmt.Errorf("storage.User.ResetPasswordById(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.User.ResetPasswordById(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Заменяет хэш пароля значением, которое не является хэшем ни одного алгоритма (ни один пароль к нему не подходит),
+// и обновляет password_changed_at. Возвращает новое password_changed_at или false, если аккаунт не найден.
+func (u *user) InvalidatePasswordById(id int) (time.Time, bool, error) {
+	query := `
+		UPDATE users
+		SET password = '!', password_changed_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING password_changed_at
+	`
+
+	var changedAt time.Time
+	if err := u.db.QueryRow(query, id).Scan(&changedAt); err != nil {
+		if err != sql.ErrNoRows {
+			return time.Time{}, false, fmt.Errorf("storage.User.InvalidatePasswordById(1): %w", err)
+		}
+		return time.Time{}, false, nil
+	}
+	return changedAt, true, nil
+}
+
+// Меняет псевдоним пользователя. Возвращает false, если псевдоним уже занят.
+func (u *user) UpdateUsernameById(id int, username string) (bool, error) {
+	query := `
+		UPDATE users
+		SET username = $2
+		WHERE id = $1
+	`
+
+	if _, err := u.db.Exec(query, id, username); err != nil {
+		if isUniqueViolation(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("storage.User.UpdateUsernameById(1): %w", err)
+	}
+	return true, nil
+}
+
+// Помечает аккаунт удаленным. Возвращает false, если аккаунт уже удален.
+func (u *user) MarkDeletedById(id int) (bool, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	res, err := u.db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("storage.User.MarkDeletedById(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.User.MarkDeletedById(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Восстанавливает аккаунт, удаленный в момент deletedAt. Возвращает false, если аккаунт уже восстановлен или удален окончательно.
+func (u *user) RestoreById(id int, deletedAt time.Time) (bool, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at = $2
+	`
+
+	res, err := u.db.Exec(query, id, deletedAt)
+	if err != nil {
+		return false, fmt.Errorf("storage.User.RestoreById(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.User.RestoreById(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Переводит аккаунт из состояния from в состояние to. Возвращает false, если пользователь не найден, удален
+// или его состояние уже не from.
+func (u *user) UpdateStatusById(id int, from, to, reason string) (bool, error) {
+	query := `
+		UPDATE users
+		SET status = $3, status_reason = $4, status_changed_at = now()
+		WHERE id = $1 AND status = $2 AND deleted_at IS NULL
+	`
+
+	res, err := u.db.Exec(query, id, from, to, reason)
+	if err != nil {
+		return false, fmt.Errorf("storage.User.UpdateStatusById(1): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage.User.UpdateStatusById(2): %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Возвращает страницу пользователей, подходящих под фильтр (по возрастанию id), и общее количество подходящих.
+func (u *user) GetList(filter *m.UserFilter) ([]*m.User, int, error) {
+	where := `
+		WHERE ($1 = '' OR username ILIKE $1 OR email ILIKE $1)
+		AND ($2 = '' OR status = $2)
+		AND ($3 = '' OR EXISTS (
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = users.id AND r.name = $3
+		))
+		AND ($4::boolean IS NULL OR (deleted_at IS NOT NULL) = $4)
+	`
+	pattern := ""
+	if filter.Query != "" {
+		pattern = "%" + escapeLike(filter.Query) + "%"
+	}
+	args := []any{pattern, filter.Status, filter.Role, filter.Deleted}
+
+	var total int
+	if err := u.db.QueryRow(`SELECT count(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("storage.User.GetList(1): %w", err)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY id LIMIT $5 OFFSET $6`
+	rows, err := u.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("storage.User.GetList(2): %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*m.User, 0)
+	for rows.Next() {
+		user := new(m.User)
+		if err := scanUser(rows, user); err != nil {
+			return nil, 0, fmt.Errorf("storage.User.GetList(3): %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("storage.User.GetList(4): %w", err)
+	}
+	return users, total, nil
+}
+
+// Окончательно удаляет аккаунты, помеченные удаленными раньше before, вместе с журналом блокировок входа по их почте.
+// Остальные данные пользователя удаляются каскадно, а его события в журнале безопасности обезличиваются.
+// Возвращает количество удаленных аккаунтов.
+func (u *user) DeleteMarkedBefore(before time.Time) (int64, error) {
+	tx, err := u.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("storage.User.DeleteMarkedBefore(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	lockoutsQuery := `
+		DELETE FROM login_lockouts
+		WHERE key IN (SELECT 'account:' || lower(email) FROM users WHERE deleted_at < $1)
+	`
+	if _, err := tx.Exec(lockoutsQuery, before); err != nil {
+		return 0, fmt.Errorf("storage.User.DeleteMarkedBefore(2): %w", err)
+	}
+
+	// Журнал событий удалять нельзя, поэтому события пользователя обезличиваются (см. миграцию audit_events).
+	auditQuery := `
+		UPDATE audit_events
+		SET ip = '', user_agent = '', metadata = metadata - 'email_hmac'
+		WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1)
+		OR actor_id IN (SELECT id FROM users WHERE deleted_at < $1)
+	`
+	if _, err := tx.Exec(auditQuery, before); err != nil {
+		return 0, fmt.Errorf("storage.User.DeleteMarkedBefore(3): %w", err)
+	}
+
+	res, err := tx.Exec(`DELETE FROM users WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("storage.User.DeleteMarkedBefore(4): %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("storage.User.DeleteMarkedBefore(5): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("storage.User.DeleteMarkedBefore(6): %w", err)
+	}
+	return affected, nil
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+    id       SERIAL PRIMARY KEY,
+    username VARCHAR(24)  NOT NULL UNIQUE,
+    email    VARCHAR(254) NOT NULL UNIQUE,
+    password TEXT         NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Уже выданные refresh-токены остаются действительными: для существующих строк берем начало эпохи.
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ NOT NULL DEFAULT to_timestamp(0);
+ALTER TABLE users ALTER COLUMN password_changed_at SET DEFAULT now();
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS pending_registrations (
+    token_hash CHAR(64)     PRIMARY KEY,        -- sha256 от кода подтверждения
+    username   VARCHAR(24)  NOT NULL,
+    email      VARCHAR(254) NOT NULL,
+    password   TEXT         NOT NULL,           -- bcrypt-хэш пароля
+    expires_at TIMESTAMPTZ  NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS pending_registrations_email_idx ON pending_registrations (email);
+CREATE INDEX IF NOT EXISTS pending_registrations_expires_at_idx ON pending_registrations (expires_at);
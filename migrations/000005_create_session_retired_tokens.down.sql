@@ -0,0 +1 @@
+DROP TABLE IF EXISTS session_retired_tokens;
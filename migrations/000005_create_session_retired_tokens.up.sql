@@ -0,0 +1,9 @@
+-- Refresh-токены, выведенные из оборота при ротации. Все токены одной сессии образуют семейство:
+-- повторное предъявление любого из них означает кражу, и сессия отзывается целиком.
+CREATE TABLE IF NOT EXISTS session_retired_tokens (
+    jti        VARCHAR(64) PRIMARY KEY,
+    session_id BIGINT      NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    retired_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS session_retired_tokens_session_id_idx ON session_retired_tokens (session_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id        INTEGER     PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret         TEXT        NOT NULL,           -- base32-секрет TOTP
+    enabled        BOOLEAN     NOT NULL DEFAULT false,
+    last_used_step BIGINT      NOT NULL DEFAULT 0, -- Последний принятый временной шаг (защита от повтора кода)
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id        BIGSERIAL   PRIMARY KEY,
+    user_id   INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash CHAR(64)    NOT NULL, -- sha256 от кода восстановления
+    used_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS user_recovery_codes_user_id_idx ON user_recovery_codes (user_id);
@@ -0,0 +1,19 @@
+-- Счетчики неудачных попыток входа (используются при lockout.store = "postgres").
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key           VARCHAR(300) PRIMARY KEY, -- "account:<email>", "ip:<ip>" или "2fa:<user_id>"
+    failures      INTEGER      NOT NULL DEFAULT 0,
+    first_fail_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    blocked_until TIMESTAMPTZ  NOT NULL DEFAULT to_timestamp(0)
+);
+
+-- Журнал блокировок входа.
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    id           BIGSERIAL    PRIMARY KEY,
+    key          VARCHAR(300) NOT NULL,
+    ip           VARCHAR(45)  NOT NULL DEFAULT '',
+    failures     INTEGER      NOT NULL,
+    locked_until TIMESTAMPTZ  NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_lockouts_key_idx ON login_lockouts (key);
@@ -0,0 +1,7 @@
+-- Счетчики ограничителя частоты запросов (используются при api.rate_limit.store = "postgres").
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key          VARCHAR(300) NOT NULL,
+    window_start TIMESTAMPTZ  NOT NULL,
+    count        INTEGER      NOT NULL DEFAULT 0,
+    PRIMARY KEY (key, window_start)
+);
@@ -0,0 +1,4 @@
+-- Пользователь создается только после подтверждения почты, поэтому существующие адреса считаются подтвержденными.
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT TRUE;
+-- Для существующих строк дата регистрации неизвестна: берем время применения миграции.
+ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+    user_id    INTEGER      PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE, -- Не больше одной смены почты за раз
+    old_email  VARCHAR(254) NOT NULL,
+    new_email  VARCHAR(254) NOT NULL,
+    expires_at TIMESTAMPTZ  NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
@@ -0,0 +1,4 @@
+-- Время удаления аккаунта пользователем. После срока хранения строка удаляется окончательно.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id          SERIAL      PRIMARY KEY,
+    name        VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name        VARCHAR(64) PRIMARY KEY, -- Вид "<ресурс>:<действие>", например users:read
+    description TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id    INTEGER     NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_roles_role_id_idx ON user_roles (role_id);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Администратор'),
+    ('user', 'Пользователь')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'Просмотр пользователей'),
+    ('users:write', 'Изменение пользователей')
+ON CONFLICT (name) DO NOTHING;
+
+-- Администратор получает все права. У роли user особых прав пока нет: ей доступны только собственные данные.
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.name FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+-- Существующие пользователи получают роль user.
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u CROSS JOIN roles r WHERE r.name = 'user'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Состояние аккаунта: pending (создан, но еще не активирован), active, suspended (временная блокировка) или banned.
+ALTER TABLE users ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active'
+    CONSTRAINT users_status_check CHECK (status IN ('pending', 'active', 'suspended', 'banned'));
+ALTER TABLE users ADD COLUMN status_reason TEXT NOT NULL DEFAULT ''; -- Причина блокировки (для поддержки)
+ALTER TABLE users ADD COLUMN status_changed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_status_idx ON users (status) WHERE status <> 'active';
@@ -0,0 +1,54 @@
+-- Журнал событий безопасности. Только добавление: изменение и удаление строк запрещены триггером.
+-- Внешних ключей нет, чтобы события сохранялись и после окончательного удаления пользователя.
+-- Вместо удаления события такого пользователя обезличиваются: ip, user_agent и metadata.email_hmac стираются,
+-- а user_id и actor_id остаются лишь номерами, которые больше ни с кем не связаны.
+-- Почта в открытом виде не хранится: для событий без известного аккаунта сохраняется только ее HMAC.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id         BIGSERIAL    PRIMARY KEY,
+    type       VARCHAR(64)  NOT NULL,
+    actor_id   INTEGER,                        -- Кто совершил действие (NULL - неизвестно)
+    user_id    INTEGER,                        -- Чей аккаунт затронут
+    ip         VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent TEXT         NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    metadata   JSONB        NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events (user_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_type_idx ON audit_events (type, id);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    -- Единственное разрешенное изменение - обезличивание, остальные поля менять нельзя.
+    IF TG_OP = 'UPDATE'
+        AND NEW.id = OLD.id
+        AND NEW.type = OLD.type
+        AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+        AND NEW.user_id IS NOT DISTINCT FROM OLD.user_id
+        AND NEW.request_id = OLD.request_id
+        AND NEW.created_at = OLD.created_at
+        AND NEW.ip = ''
+        AND NEW.user_agent = ''
+        AND NEW.metadata = OLD.metadata - 'email_hmac' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'Просмотр журнала событий')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'audit:read' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
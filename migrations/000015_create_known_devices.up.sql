@@ -0,0 +1,13 @@
+-- Устройства (сочетания user agent и ip), с которых пользователь уже входил.
+-- Вход с устройства не из списка сопровождается уведомлением на почту.
+CREATE TABLE IF NOT EXISTS known_devices (
+    id            BIGSERIAL    PRIMARY KEY,
+    user_id       INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_agent    TEXT         NOT NULL DEFAULT '',
+    ip            VARCHAR(45)  NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    last_seen_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- user agent не ограничен по длине, поэтому в уникальный индекс попадает его хэш.
+CREATE UNIQUE INDEX IF NOT EXISTS known_devices_user_device_idx ON known_devices (user_id, ip, md5(user_agent));
@@ -0,0 +1,10 @@
+-- Использованные одноразовые коды (по jti). Запись нужна, только пока код не истек:
+-- истекшие записи удаляются фоновой задачей.
+CREATE TABLE IF NOT EXISTS consumed_tokens (
+    jti         VARCHAR(64)  PRIMARY KEY,
+    purpose     VARCHAR(32)  NOT NULL,
+    expires_at  TIMESTAMPTZ  NOT NULL,
+    consumed_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS consumed_tokens_expires_at_idx ON consumed_tokens (expires_at);
@@ -4,6 +4,8 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/smtp"
+	"net/url"
+	"time"
 
 	"github.com/lesienchik/vk__test/internal/config"
 )
@@ -24,6 +26,62 @@ func New(cfg *config.Email) *Email {
 
 // Отправляет код подтверждения на почту пользователя, чтобы тот мог завершить регистрацию.
 func (m *Email) SendConfirmCode(to, verifyCode string) error {
+	if err := m.send(to, m.getConfirmMessage(to, verifyCode)); err != nil {
+		return fmt.Errorf("email.SendConfirmCode(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет на почту пользователя ссылку для сброса пароля.
+func (m *Email) SendResetPassCode(to, resetCode string) error {
+	if err := m.send(to, m.getResetPassMessage(to, resetCode)); err != nil {
+		return fmt.Errorf("email.SendResetPassCode(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет на новый адрес ссылку для подтверждения смены почты.
+func (m *Email) SendChangeEmailCode(to, confirmCode string) error {
+	if err := m.send(to, m.getChangeEmailMessage(to, confirmCode)); err != nil {
+		return fmt.Errorf("email.SendChangeEmailCode(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет на старый адрес уведомление о запрошенной смене почты со ссылкой для отмены.
+func (m *Email) SendChangeEmailNotice(to, newEmail, cancelCode string) error {
+	if err := m.send(to, m.getChangeEmailNoticeMessage(to, newEmail, cancelCode)); err != nil {
+		return fmt.Errorf("email.SendChangeEmailNotice(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет уведомление об удалении аккаунта со ссылкой для его восстановления.
+func (m *Email) SendDeletionNotice(to, restoreCode string, purgeAt time.Time) error {
+	if err := m.send(to, m.getDeletionNoticeMessage(to, restoreCode, purgeAt)); err != nil {
+		return fmt.Errorf("email.SendDeletionNotice(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет на почту пользователя одноразовую ссылку для входа без пароля.
+func (m *Email) SendMagicLink(to, magicCode string) error {
+	if err := m.send(to, m.getMagicLinkMessage(to, magicCode)); err != nil {
+		return fmt.Errorf("email.SendMagicLink(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет уведомление о входе с нового устройства со ссылкой для завершения всех сессий.
+func (m *Email) SendNewDeviceNotice(to, device, ip string, loginAt time.Time, revokeCode string) error {
+	if err := m.send(to, m.getNewDeviceNoticeMessage(to, device, ip, loginAt, revokeCode)); err != nil {
+		return fmt.Errorf("email.SendNewDeviceNotice(1): %w", err)
+	}
+	return nil
+}
+
+// Отправляет готовое сообщение на указанную почту.
+func (m *Email) send(to string, message []byte) error {
 	// Настройка SMTP клиента.
 	var (
 		smtpHost = "smtp.mail.ru"
@@ -36,42 +94,39 @@ func (m *Email) SendConfirmCode(to, verifyCode string) error {
 		ServerName:         smtpHost,
 	})
 	if err != nil {
-		return fmt.Errorf("email.SendConfirmCode(1): %w", err)
+		return fmt.Errorf("email.send(1): %w", err)
 	}
 
 	smtpClient, err := smtp.NewClient(conn, smtpHost)
 	if err != nil {
-		return fmt.Errorf("email.SendConfirmCode(2): %w", err)
+		return fmt.Errorf("email.send(2): %w", err)
 	}
 
 	// Аутентификация.
 	auth := smtp.PlainAuth("", m.addr, m.password, smtpHost)
 	if err := smtpClient.Auth(auth); err != nil {
-		return fmt.Errorf("email.SendConfirmCode(3): %w", err)
+		return fmt.Errorf("email.send(3): %w", err)
 	}
 
 	// Установка отправителя и получателя.
 	if err := smtpClient.Mail(m.addr); err != nil {
-		return fmt.Errorf("email.SendConfirmCode(4): %w", err)
+		return fmt.Errorf("email.send(4): %w", err)
 	}
 	if err := smtpClient.Rcpt(to); err != nil {
-		return fmt.Errorf("email.SendConfirmCode(5): %w", err)
+		return fmt.Errorf("email.send(5): %w", err)
 	}
 
-	// Формирование сообщения.
-	message := m.getConfirmMessage(to, verifyCode)
-
 	// Отправка сообщения
 	w, err := smtpClient.Data()
 	if err != nil {
-		return fmt.Errorf("email.SendConfirmCode(6): %w", err)
+		return fmt.Errorf("email.send(6): %w", err)
 	}
 
 	if _, err := w.Write(message); err != nil {
-		return fmt.Errorf("email.SendConfirmCode(7): %w", err)
+		return fmt.Errorf("email.send(7): %w", err)
 	}
 	if err := w.Close(); err != nil {
-		return fmt.Errorf("email.SendConfirmCode(8): %w", err)
+		return fmt.Errorf("email.send(8): %w", err)
 	}
 	return smtpClient.Quit()
 }
@@ -92,3 +147,121 @@ func (m *Email) getConfirmMessage(to, verifyCode string) []byte {
 Команда vktest`, to, link)
 	return []byte(subject + "\n" + body)
 }
+
+// Формирует сообщение со ссылкой для сброса пароля.
+func (m *Email) getResetPassMessage(to, resetCode string) []byte {
+	link := fmt.Sprintf("%s/reset-password?code=%s", m.site, url.QueryEscape(resetCode))
+	subject := "Subject: Восстановление пароля в vktest\n"
+	body := fmt.Sprintf(`Здравствуйте, %s!
+
+Мы получили запрос на восстановление пароля для Вашей учетной записи в vktest.
+
+Чтобы задать новый пароль, перейдите по ссылке ниже (ссылка действительна 10 минут и может быть использована только один раз):
+
+%s
+
+Если Вы не запрашивали восстановление пароля, просто проигнорируйте это письмо.
+
+С наилучшими пожеланиями,
+Команда vktest`, to, link)
+	return []byte(subject + "\n" + body)
+}
+
+// Формирует сообщение со ссылкой для подтверждения нового адреса почты.
+func (m *Email) getChangeEmailMessage(to, confirmCode string) []byte {
+	link := fmt.Sprintf("%s/confirm-email?code=%s", m.site, url.QueryEscape(confirmCode))
+	subject := "Subject: Подтверждение новой почты в vktest\n"
+	body := fmt.Sprintf(`Здравствуйте, %s!
+
+Мы получили запрос на привязку этого адреса к Вашей учетной записи в vktest.
+
+Чтобы подтвердить новый адрес, перейдите по ссылке ниже (ссылка действительна 1 час):
+
+%s
+
+Если Вы не запрашивали смену почты, просто проигнорируйте это письмо.
+
+С наилучшими пожеланиями,
+Команда vktest`, to, link)
+	return []byte(subject + "\n" + body)
+}
+
+// Формирует уведомление о смене почты со ссылкой для отмены.
+func (m *Email) getChangeEmailNoticeMessage(to, newEmail, cancelCode string) []byte {
+	link := fmt.Sprintf("%s/cancel-email-change?code=%s", m.site, url.QueryEscape(cancelCode))
+	subject := "Subject: Смена почты в vktest\n"
+	body := fmt.Sprintf(`Здравствуйте, %s!
+
+Для Вашей учетной записи в vktest запрошена смена почты на адрес %s.
+
+Если это были не Вы, отмените смену почты по ссылке ниже (ссылка действительна, пока новый адрес не подтвержден) и смените пароль:
+
+%s
+
+С наилучшими пожеланиями,
+Команда vktest`, to, newEmail, link)
+	return []byte(subject + "\n" + body)
+}
+
+// Формирует уведомление об удалении аккаунта со ссылкой для восстановления.
+func (m *Email) getDeletionNoticeMessage(to, restoreCode string, purgeAt time.Time) []byte {
+	link := fmt.Sprintf("%s/restore-account?code=%s", m.site, url.QueryEscape(restoreCode))
+	subject := "Subject: Удаление аккаунта в vktest\n"
+	body := fmt.Sprintf(`Здравствуйте, %s!
+
+Ваша учетная запись в vktest удалена. Все данные будут окончательно удалены %s (UTC).
+
+До этого момента аккаунт можно восстановить по ссылке ниже:
+
+%s
+
+С наилучшими пожеланиями,
+Команда vktest`, to, purgeAt.UTC().Format("02.01.2006 15:04"), link)
+	return []byte(subject + "\n" + body)
+}
+
+// Формирует уведомление о входе с нового устройства. Для смены пароля письмо ведет на страницу восстановления:
+// код сброса пароля не должен долго лежать в почте.
+func (m *Email) getNewDeviceNoticeMessage(to, device, ip string, loginAt time.Time, revokeCode string) []byte {
+	revokeLink := fmt.Sprintf("%s/revoke-sessions?code=%s", m.site, url.QueryEscape(revokeCode))
+	forgotLink := fmt.Sprintf("%s/forgot-password", m.site)
+	subject := "Subject: Вход в vktest с нового устройства\n"
+	body := fmt.Sprintf(`Здравствуйте, %s!
+
+В Вашу учетную запись в vktest выполнен вход с нового устройства:
+
+Время: %s (UTC)
+Устройство: %s
+IP-адрес: %s
+
+Если это были Вы, ничего делать не нужно.
+
+Если это были не Вы, завершите все сессии по ссылке ниже (она действительна 24 часа и срабатывает один раз):
+
+%s
+
+и смените пароль на странице восстановления пароля:
+
+%s
+
+С наилучшими пожеланиями,
+Команда vktest`, to, loginAt.UTC().Format("02.01.2006 15:04"), device, ip, revokeLink, forgotLink)
+	return []byte(subject + "\n" + body)
+}
+
+// Формирует сообщение со ссылкой для входа без пароля.
+func (m *Email) getMagicLinkMessage(to, magicCode string) []byte {
+	link := fmt.Sprintf("%s/magic-login?code=%s", m.site, url.QueryEscape(magicCode))
+	subject := "Subject: Вход в vktest\n"
+	body := fmt.Sprintf(`Здравствуйте, %s!
+
+Чтобы войти в vktest без пароля, перейдите по ссылке ниже (ссылка действительна 5 минут и может быть использована только один раз):
+
+%s
+
+Если Вы не запрашивали вход, просто проигнорируйте это письмо.
+
+С наилучшими пожеланиями,
+Команда vktest`, to, link)
+	return []byte(subject + "\n" + body)
+}
@@ -2,8 +2,10 @@ package hashes
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +14,6 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -31,35 +32,36 @@ const (
 	HashExpires
 )
 
-func HashPassword(password string) ([]byte, error) {
-	if len(password) == 0 {
-		return nil, fmt.Errorf("hashes.HashPassword(1): password is empty")
+// Генерирует случайный непрозрачный токен из size байт (в base64 без паддинга, безопасный для url).
+func GenRandomToken(size int) (string, error) {
+	if size <= 0 {
+		return "", errors.New("hashes.GenRandomToken(1): size must be positive")
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, fmt.Errorf("hashes.HashPassword(2): %w", err)
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("hashes.GenRandomToken(2): %w", err)
 	}
-	return hash, nil
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
-func CompareHashAndPassword(hashedPassword, password string) error {
-	if len(hashedPassword) == 0 {
-		return fmt.Errorf("hashes.CompareHashAndPassword(1): hashedPassword is empty")
-	}
-	if len(password) == 0 {
-		return fmt.Errorf("hashes.CompareHashAndPassword(2): password is empty")
-	}
+// Возвращает sha256 от токена в hex. Используется, чтобы не хранить токены в БД в открытом виде.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
-		return fmt.Errorf("hashes.CompareHashAndPassword(3): %w", err)
-	}
-	return nil
+// Хэширует строку HMAC-SHA256 с ключом key. В отличие от HashToken, без ключа по хэшу нельзя проверить догадку.
+func HmacToken(token string, key []byte) string {
+	hmacHash := hmac.New(sha256.New, key)
+	hmacHash.Write([]byte(token))
+	return hex.EncodeToString(hmacHash.Sum(nil))
 }
 
 // Генерирует хэш из любых входных структур (с включенной сигнатурой json).
-// Принимает время жизни генерируемого хэша.
-func HmacGenHash(in any, ttl time.Duration, secret string) (string, error) {
+// Принимает время жизни генерируемого хэша. Хэш подписывается текущим ключом связки и начинается с его id
+// (<kid>.<сообщение>.<подпись>); хэши SECRET_KEY id не содержат.
+func HmacGenHash(in any, ttl time.Duration, ring *KeyRing) (string, error) {
 	if ttl == 0 {
 		ttl = ExpiresDefault
 	}
@@ -72,7 +74,11 @@ func HmacGenHash(in any, ttl time.Duration, secret string) (string, error) {
 
 	message := fmt.Sprintf("%s|%d", data, expiration)
 
-	hmacHash := hmac.New(sha256.New, []byte(secret))
+	key, err := ring.hmacSigner()
+	if err != nil {
+		return "", fmt.Errorf("hashes.HmacGenHash (2): %w", err)
+	}
+	hmacHash := hmac.New(sha256.New, key.secret)
 	hmacHash.Write([]byte(message))
 	signature := hmacHash.Sum(nil)
 
@@ -81,80 +87,98 @@ func HmacGenHash(in any, ttl time.Duration, secret string) (string, error) {
 		base64.RawStdEncoding.EncodeToString([]byte(message)),
 		base64.RawStdEncoding.EncodeToString(signature),
 	)
+	if key.Id != legacyKeyId {
+		hash = key.Id + "." + hash
+	}
 	return hash, nil
 }
 
-func HmacParseAndValidateHash(hash string, out any, secret string) (byte, error) {
+func HmacParseAndValidateHash(hash string, out any, ring *KeyRing) (byte, error) {
 	parts := strings.Split(hash, ".")
+	kid := legacyKeyId
+	if len(parts) == 3 {
+		kid, parts = parts[0], parts[1:]
+	}
 	if len(parts) != 2 {
 		return HashError, errors.New("hashes.HmacParseAndValidateHash(1): invalid format hash")
 	}
 
+	key, ok := ring.hmacVerifier(kid)
+	if !ok {
+		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(2): unknown or retired key id [%s]", kid)
+	}
+
 	message, err := base64.RawStdEncoding.DecodeString(parts[0])
 	if err != nil {
-		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(2): %w", err)
+		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(3): %w", err)
 	}
 
 	signature, err := base64.RawStdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(3): %w", err)
+		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(4): %w", err)
 	}
 
-	hmacHash := hmac.New(sha256.New, []byte(secret))
+	hmacHash := hmac.New(sha256.New, key.secret)
 	hmacHash.Write(message)
 	expectedSignature := hmacHash.Sum(nil)
 
 	if !hmac.Equal(signature, expectedSignature) {
-		return HashError, errors.New("hashes.HmacParseAndValidateHash(4): invalid hash signature")
+		return HashError, errors.New("hashes.HmacParseAndValidateHash(5): invalid hash signature")
 	}
 
 	partsMessage := strings.SplitN(string(message), "|", 2)
 	if len(partsMessage) != 2 {
-		return HashError, errors.New("hashes.HmacParseAndValidateHash(5): invalid message format")
+		return HashError, errors.New("hashes.HmacParseAndValidateHash(6): invalid message format")
 	}
 
 	expiration, err := strconv.ParseInt(partsMessage[1], 10, 64)
 	if err != nil || time.Now().Unix() > expiration {
-		return HashExpires, errors.New("hashes.HmacParseAndValidateHash(6): hash has expired")
+		return HashExpires, errors.New("hashes.HmacParseAndValidateHash(7): hash has expired")
 	}
 
 	if err := json.Unmarshal([]byte(partsMessage[0]), &out); err != nil {
-		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(7): %w", err)
+		return HashError, fmt.Errorf("hashes.HmacParseAndValidateHash(8): %w", err)
 	}
 
 	return HashValid, nil
 }
 
 // Генерирует jwt-токен, в зависимости от переданного claims.
-func JwtGenToken(claims jwt.Claims, secret string) (token string, err error) {
-	jwtT := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err = jwtT.SignedString([]byte(secret))
+func JwtGenToken(claims jwt.Claims, ring *KeyRing) (token string, err error) {
+	token, err = ring.signJwt(claims)
 	if err != nil {
 		return "", fmt.Errorf("hashes.JwtGenToken(1): %w", err)
 	}
 	return token, nil
 }
 
-// Расшифровывает и проверяет JWT токен с любыми claims.
-func JwtParseAndValidateToken(token string, claims jwt.Claims, secret string) (jwt.Claims, byte, error) {
-	jwtToken, err := jwt.ParseWithClaims(token, claims, func(jwtToken *jwt.Token) (interface{}, error) {
-		if _, ok := jwtToken.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("hashes.JwtParseAndValidateToken(1): unexpected signing method [%v]", jwtToken.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
+// Расшифровывает и проверяет JWT токен с любыми claims. Дополнительные проверки (iss, aud, допустимое
+// расхождение часов) задаются опциями парсера. Для истекшего токена claims заполняются, а статус
+// JwtTokenExpires возвращается, только если все остальные проверки пройдены.
+func JwtParseAndValidateToken(token string, claims jwt.Claims, ring *KeyRing, opts ...jwt.ParserOption) (jwt.Claims, byte, error) {
+	jwtToken, err := jwt.ParseWithClaims(token, claims, ring.jwtKeyFunc, opts...)
 
 	if err != nil {
 		// Проверяем, не истек ли срок действия токена.
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, JwtTokenExpires, fmt.Errorf("hashes.JwtParseAndValidateToken(2): token has expired")
+		if errors.Is(err, jwt.ErrTokenExpired) && jwtValidAtExpiration(claims, opts) {
+			return nil, JwtTokenExpires, fmt.Errorf("hashes.JwtParseAndValidateToken(1): token has expired")
 		}
-		return nil, JwtTokenError, fmt.Errorf("hashes.JwtParseAndValidateToken(3): %w", err)
+		return nil, JwtTokenError, fmt.Errorf("hashes.JwtParseAndValidateToken(2): %w", err)
 	}
 
 	if !jwtToken.Valid {
-		return nil, JwtTokenError, fmt.Errorf("hashes.JwtParseAndValidateToken(4): invalid token")
+		return nil, JwtTokenError, fmt.Errorf("hashes.JwtParseAndValidateToken(3): invalid token")
 	}
 
 	return claims, JwtTokenValid, nil
 }
+
+// Проверяет claims истекшего токена на момент перед его истечением: кроме срока действия, ошибок быть не должно.
+func jwtValidAtExpiration(claims jwt.Claims, opts []jwt.ParserOption) bool {
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return false
+	}
+	opts = append(opts[:len(opts):len(opts)], jwt.WithTimeFunc(func() time.Time { return exp.Add(-time.Nanosecond) }))
+	return jwt.NewValidator(opts...).Validate(claims) == nil
+}
@@ -0,0 +1,242 @@
+package hashes
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Асимметричный ключ подписи jwt.
+type JwtKey struct {
+	keyMeta
+	method  jwt.SigningMethod
+	private crypto.PrivateKey // nil - ключ только для проверки
+	public  crypto.PublicKey
+}
+
+// Открытый ключ в формате JWK (RFC 7517).
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+	Crv string `json:"crv,omitempty"` // EC, OKP
+	X   string `json:"x,omitempty"`   // EC, OKP
+	Y   string `json:"y,omitempty"`   // EC
+}
+
+// Набор открытых ключей (ответ /.well-known/jwks.json).
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// Подписывает claims текущим ключом связки (с заголовком kid).
+func (r *KeyRing) signJwt(claims jwt.Claims) (string, error) {
+	key, ok := r.jwtSigner()
+	if !ok {
+		hmacKey, err := r.hmacSigner()
+		if err != nil {
+			return "", fmt.Errorf("hashes.KeyRing.signJwt(1): %w", err)
+		}
+		jwtT := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		if hmacKey.Id != legacyKeyId {
+			jwtT.Header["kid"] = hmacKey.Id
+		}
+		return jwtT.SignedString(hmacKey.secret)
+	}
+
+	jwtT := jwt.NewWithClaims(key.method, claims)
+	jwtT.Header["kid"] = key.Id
+	return jwtT.SignedString(key.private)
+}
+
+// Возвращает ключ проверки подписи по заголовкам токена. Алгоритм токена должен совпадать с алгоритмом ключа,
+// иначе открытый ключ можно было бы выдать за секрет HS256.
+func (r *KeyRing) jwtKeyFunc(jwtToken *jwt.Token) (interface{}, error) {
+	kid, _ := jwtToken.Header["kid"].(string)
+
+	if jwtToken.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if !r.acceptHmacJwt() {
+			return nil, errors.New("hashes.KeyRing.jwtKeyFunc(1): hmac tokens are not accepted")
+		}
+		key, ok := r.hmacVerifier(kid)
+		if !ok {
+			return nil, fmt.Errorf("hashes.KeyRing.jwtKeyFunc(2): unknown or retired key id [%s]", kid)
+		}
+		return key.secret, nil
+	}
+
+	key, ok := r.jwtVerifier(kid)
+	if !ok {
+		return nil, fmt.Errorf("hashes.KeyRing.jwtKeyFunc(3): unknown or retired key id [%s]", kid)
+	}
+	if jwtToken.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("hashes.KeyRing.jwtKeyFunc(4): unexpected signing method [%v]", jwtToken.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// Возвращает открытые ключи для проверки токенов другими сервисами (кроме погашенных).
+func (r *KeyRing) Jwks() *Jwks {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jwks := &Jwks{Keys: make([]Jwk, 0, len(r.jwt))}
+	for _, key := range r.jwt {
+		if key.State != KeyRetired {
+			jwks.Keys = append(jwks.Keys, key.jwk())
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+func (k *JwtKey) jwk() Jwk {
+	jwk := Jwk{Kid: k.Id, Use: "sig", Alg: k.method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// Загружает ключ jwt из PEM-файла. Относительные пути считаются от каталога dir (каталог файла связки).
+func loadJwtKey(entry *KeyRingEntry, dir string) (*JwtKey, error) {
+	key := &JwtKey{keyMeta: entry.meta()}
+
+	switch {
+	case entry.PrivateKey != "":
+		block, err := readPem(resolvePath(dir, entry.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("hashes.loadJwtKey(1): %w", err)
+		}
+		if key.private, err = parsePrivateKey(block); err != nil {
+			return nil, fmt.Errorf("hashes.loadJwtKey(2): %w", err)
+		}
+		signer, ok := key.private.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("hashes.loadJwtKey(3): unsupported private key type")
+		}
+		key.public = signer.Public()
+	case entry.PublicKey != "":
+		block, err := readPem(resolvePath(dir, entry.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("hashes.loadJwtKey(4): %w", err)
+		}
+		if key.public, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("hashes.loadJwtKey(5): %w", err)
+		}
+	default:
+		return nil, errors.New("hashes.loadJwtKey(6): neither private nor public key is set")
+	}
+
+	method, err := jwtSigningMethod(key.public)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.loadJwtKey(7): %w", err)
+	}
+	key.method = method
+	return key, nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Генерирует новый ключ Ed25519 для jwt и сохраняет закрытую часть в PEM-файл в каталоге dir.
+func GenJwtKeyEntry(dir string) (*KeyRingEntry, error) {
+	id, err := GenKeyId(KeyKindJwt)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.GenJwtKeyEntry(1): %w", err)
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.GenJwtKeyEntry(2): %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.GenJwtKeyEntry(3): %w", err)
+	}
+
+	name := id + ".pem"
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, fmt.Errorf("hashes.GenJwtKeyEntry(4): %w", err)
+	}
+	return &KeyRingEntry{Id: id, PrivateKey: name}, nil
+}
+
+func readPem(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.readPem(1): %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("hashes.readPem(2): no pem block in %s", path)
+	}
+	return block, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.PrivateKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+}
+
+// Определяет алгоритм подписи по типу открытого ключа.
+func jwtSigningMethod(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch public.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, errors.New("hashes.jwtSigningMethod(1): unsupported curve")
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, errors.New("hashes.jwtSigningMethod(2): unsupported key type")
+}
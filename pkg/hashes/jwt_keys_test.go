@@ -0,0 +1,289 @@
+package hashes
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesienchik/vk__test/internal/config"
+)
+
+// Записывает закрытый ключ в PEM-файл (PKCS#8) и возвращает путь к нему.
+func writePrivateKey(t *testing.T, key crypto.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func testClaims() jwt.Claims {
+	return jwt.RegisteredClaims{
+		Subject:   "1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+// Записывает файл связки во временный каталог и загружает из него связку ключей.
+func newTestKeyRing(t *testing.T, file *KeyRingFile, jwtCfg *config.Jwt) *KeyRing {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, WriteKeyRingFile(path, file))
+
+	ring, err := NewKeyRing(&config.KeyRing{Path: path}, jwtCfg, "secret")
+	require.NoError(t, err)
+	return ring
+}
+
+func jwtEntry(t *testing.T, id string, key crypto.PrivateKey) *KeyRingEntry {
+	return &KeyRingEntry{Id: id, State: KeyActive, PrivateKey: writePrivateKey(t, key), CreatedAt: time.Now().Add(-time.Hour)}
+}
+
+func TestJwtKeysSignAndParse(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	requires.NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	requires.NoError(err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+
+	testTable := []struct {
+		desc     string            // Описание теста
+		input    crypto.PrivateKey // Входные данные
+		expected string            // Ожидаемый алгоритм
+	}{
+		{
+			desc:     "RSA",
+			input:    rsaKey,
+			expected: "RS256",
+		},
+		{
+			desc:     "ECDSA",
+			input:    ecKey,
+			expected: "ES256",
+		},
+		{
+			desc:     "Ed25519",
+			input:    edKey,
+			expected: "EdDSA",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			ring := newTestKeyRing(t, &KeyRingFile{Jwt: []*KeyRingEntry{jwtEntry(t, "k1", tc.input)}}, &config.Jwt{})
+
+			// Act
+			token, err := JwtGenToken(testClaims(), ring)
+			requires.NoError(err)
+			_, status, err := JwtParseAndValidateToken(token, &jwt.RegisteredClaims{}, ring)
+
+			// Assert
+			requires.NoError(err)
+			requires.Equal(JwtTokenValid, status)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			requires.NoError(err)
+			requires.Equal(tc.expected, parsed.Method.Alg())
+			requires.Equal("k1", parsed.Header["kid"])
+
+			jwks := ring.Jwks()
+			requires.Len(jwks.Keys, 1)
+			requires.Equal("k1", jwks.Keys[0].Kid)
+			requires.Equal(tc.expected, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestJwtKeysRejectHmac(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+	file := &KeyRingFile{Jwt: []*KeyRingEntry{jwtEntry(t, "k1", edKey)}}
+
+	hmacRing, err := NewKeyRing(&config.KeyRing{}, &config.Jwt{}, "secret")
+	requires.NoError(err)
+	legacyToken, err := JwtGenToken(testClaims(), hmacRing)
+	requires.NoError(err)
+
+	strict := newTestKeyRing(t, file, &config.Jwt{})
+	legacy := newTestKeyRing(t, file, &config.Jwt{LegacyHmac: true})
+
+	// Act
+	_, _, strictErr := JwtParseAndValidateToken(legacyToken, &jwt.RegisteredClaims{}, strict)
+	_, _, legacyErr := JwtParseAndValidateToken(legacyToken, &jwt.RegisteredClaims{}, legacy)
+
+	// Assert
+	requires.Error(strictErr)
+	requires.NoError(legacyErr)
+	requires.Empty(hmacRing.Jwks().Keys) // Общий секрет не публикуется
+}
+
+func TestJwtKeysFromConfig(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	_, configKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+	_, fileKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+
+	jwtCfg := &config.Jwt{Keys: []config.JwtKey{{Id: "config", PrivateKey: writePrivateKey(t, configKey)}}}
+	configRing, err := NewKeyRing(&config.KeyRing{}, jwtCfg, "secret")
+	requires.NoError(err)
+	configToken, err := JwtGenToken(testClaims(), configRing)
+	requires.NoError(err)
+
+	// Act
+	ring := newTestKeyRing(t, &KeyRingFile{Jwt: []*KeyRingEntry{jwtEntry(t, "file", fileKey)}}, jwtCfg)
+	fileToken, err := JwtGenToken(testClaims(), ring)
+	requires.NoError(err)
+
+	// Assert
+	for token, kid := range map[string]string{configToken: "config", fileToken: "file"} {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+		requires.NoError(err)
+		requires.Equal(kid, parsed.Header["kid"])
+
+		_, status, err := JwtParseAndValidateToken(token, &jwt.RegisteredClaims{}, ring)
+		requires.NoError(err)
+		requires.Equal(JwtTokenValid, status)
+	}
+	requires.Len(ring.Jwks().Keys, 2)
+
+	_, err = NewKeyRing(&config.KeyRing{}, &config.Jwt{Keys: jwtCfg.Keys, SigningKey: "unknown"}, "secret")
+	requires.Error(err)
+}
+
+func TestJwtKeysFirstKeyPending(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+	entry := jwtEntry(t, "k1", edKey)
+	entry.CreatedAt = time.Now() // Ключ еще не разошелся по репликам
+	ring := newTestKeyRing(t, &KeyRingFile{Jwt: []*KeyRingEntry{entry}}, &config.Jwt{})
+
+	// Act
+	token, err := JwtGenToken(testClaims(), ring)
+	requires.NoError(err)
+	_, status, err := JwtParseAndValidateToken(token, &jwt.RegisteredClaims{}, ring)
+
+	// Assert
+	requires.NoError(err)
+	requires.Equal(JwtTokenValid, status)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	requires.NoError(err)
+	requires.Equal("HS256", parsed.Method.Alg()) // Пока ключ не готов, подписывает SECRET_KEY
+	requires.Len(ring.Jwks().Keys, 1)            // но сам ключ уже публикуется
+}
+
+func TestJwtKeysRotation(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	requires.NoError(err)
+
+	file := &KeyRingFile{Jwt: []*KeyRingEntry{jwtEntry(t, "old", oldKey)}}
+	ring := newTestKeyRing(t, file, &config.Jwt{})
+	oldToken, err := JwtGenToken(testClaims(), ring)
+	requires.NoError(err)
+
+	// Act
+	requires.NoError(file.Rotate(KeyKindJwt, &KeyRingEntry{Id: "new", PrivateKey: writePrivateKey(t, newKey)}, time.Hour))
+	requires.NoError(WriteKeyRingFile(ring.path, file))
+	requires.NoError(ring.Reload())
+	newToken, err := JwtGenToken(testClaims(), ring)
+	requires.NoError(err)
+
+	// Assert
+	for _, token := range []string{oldToken, newToken} {
+		_, status, err := JwtParseAndValidateToken(token, &jwt.RegisteredClaims{}, ring)
+		requires.NoError(err)
+		requires.Equal(JwtTokenValid, status)
+	}
+	requires.Len(ring.Jwks().Keys, 2) // Новый ключ публикуется до того, как начнет подписывать
+
+	requires.NoError(file.Retire(KeyKindJwt, "old"))
+	requires.NoError(WriteKeyRingFile(ring.path, file))
+	requires.NoError(ring.Reload())
+
+	_, _, err = JwtParseAndValidateToken(oldToken, &jwt.RegisteredClaims{}, ring)
+	requires.Error(err)
+	requires.Len(ring.Jwks().Keys, 1)
+}
+
+func TestJwtParseExpired(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	ring, err := NewKeyRing(&config.KeyRing{}, &config.Jwt{}, "secret")
+	requires.NoError(err)
+
+	expiredClaims := func(aud string) jwt.Claims {
+		return jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{aud},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		}
+	}
+
+	testTable := []struct {
+		desc     string     // Описание теста
+		input    jwt.Claims // Входные данные
+		expected byte       // Ожидаемый статус
+	}{
+		{
+			desc:     "Expired, otherwise valid",
+			input:    expiredClaims("api"),
+			expected: JwtTokenExpires,
+		},
+		{
+			desc:     "Expired with wrong audience",
+			input:    expiredClaims("other"),
+			expected: JwtTokenError,
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			token, err := JwtGenToken(tc.input, ring)
+			requires.NoError(err)
+
+			// Act
+			_, status, err := JwtParseAndValidateToken(token, &jwt.RegisteredClaims{}, ring, jwt.WithAudience("api"))
+
+			// Assert
+			requires.Error(err)
+			requires.Equal(tc.expected, status)
+		})
+	}
+
+	// Допустимое расхождение часов
+	token, err := JwtGenToken(expiredClaims("api"), ring)
+	requires.NoError(err)
+	_, status, err := JwtParseAndValidateToken(token, &jwt.RegisteredClaims{}, ring, jwt.WithLeeway(2*time.Minute))
+	requires.NoError(err)
+	requires.Equal(JwtTokenValid, status)
+}
@@ -0,0 +1,492 @@
+package hashes
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lesienchik/vk__test/internal/config"
+)
+
+// Состояние ключа в связке.
+type KeyState string
+
+const (
+	KeyActive     KeyState = "active"      // Подписывает новые токены и коды
+	KeyVerifyOnly KeyState = "verify_only" // Только проверяет ранее выданные
+	KeyRetired    KeyState = "retired"     // Подписанное им больше не принимается
+)
+
+// Виды ключей в связке.
+const (
+	KeyKindHmac = "hmac"
+	KeyKindJwt  = "jwt"
+)
+
+const (
+	legacyKeyId            = ""  // Id ключа SECRET_KEY: токены и коды без kid
+	hmacKeySize            = 32  // Размер нового hmac-ключа в байтах
+	defaultReloadInterval  = 60  // сек
+	defaultActivationDelay = 120 // сек
+)
+
+// Запись о ключе в файле связки.
+type KeyRingEntry struct {
+	Id         string     `json:"id"`
+	State      KeyState   `json:"state"`
+	Secret     string     `json:"secret,omitempty"`      // hmac: секрет в base64
+	PrivateKey string     `json:"private_key,omitempty"` // jwt: путь к PEM закрытого ключа (относительно файла связки)
+	PublicKey  string     `json:"public_key,omitempty"`  // jwt: путь к PEM открытого ключа, если закрытого нет
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"` // Когда ключ перестал подписывать
+}
+
+// Содержимое файла связки.
+type KeyRingFile struct {
+	Hmac []*KeyRingEntry `json:"hmac"`
+	Jwt  []*KeyRingEntry `json:"jwt"`
+}
+
+// Общие сведения о ключе, по которым выбирается ключ подписи.
+type keyMeta struct {
+	Id        string
+	State     KeyState
+	CreatedAt time.Time
+	RotatedAt *time.Time
+}
+
+type hmacKey struct {
+	keyMeta
+	secret []byte
+}
+
+// Связка ключей: подписывает текущим ключом и проверяет любым непогашенным, выбирая ключ по kid.
+// Файл связки перечитывается на ходу (Reload), поэтому ключи меняются без перезапуска и без
+// одномоментной потери всех выданных токенов.
+type KeyRing struct {
+	mu              sync.RWMutex
+	path            string
+	legacy          *hmacKey // SECRET_KEY
+	activationDelay time.Duration
+	reloadInterval  time.Duration
+	legacyHmacJwt   bool // Принимать jwt HS256, когда есть асимметричные ключи
+
+	hmac      map[string]*hmacKey
+	jwt       map[string]*JwtKey
+	configJwt map[string]*JwtKey // Ключи jwt из конфига (jwt.keys), не меняются при Reload
+}
+
+func NewKeyRing(cfg *config.KeyRing, jwtCfg *config.Jwt, secret string) (*KeyRing, error) {
+	legacyState := KeyVerifyOnly
+	if cfg.LegacySecret != "" {
+		legacyState = KeyState(cfg.LegacySecret)
+	}
+	if legacyState != KeyVerifyOnly && legacyState != KeyRetired {
+		return nil, fmt.Errorf("hashes.NewKeyRing(1): invalid legacy secret state %s", cfg.LegacySecret)
+	}
+
+	configJwt, err := loadConfigJwtKeys(jwtCfg)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.NewKeyRing(2): %w", err)
+	}
+
+	ring := &KeyRing{
+		path:            cfg.Path,
+		legacy:          &hmacKey{keyMeta: keyMeta{Id: legacyKeyId, State: legacyState}, secret: []byte(secret)},
+		activationDelay: time.Duration(cfg.ActivationDelay) * time.Second,
+		reloadInterval:  time.Duration(cfg.ReloadInterval) * time.Second,
+		legacyHmacJwt:   jwtCfg.LegacyHmac,
+		hmac:            make(map[string]*hmacKey),
+		jwt:             configJwt,
+		configJwt:       configJwt,
+	}
+	if cfg.ActivationDelay <= 0 {
+		ring.activationDelay = defaultActivationDelay * time.Second
+	}
+	if cfg.ReloadInterval <= 0 {
+		ring.reloadInterval = defaultReloadInterval * time.Second
+	}
+
+	if err := ring.Reload(); err != nil {
+		return nil, fmt.Errorf("hashes.NewKeyRing(3): %w", err)
+	}
+	return ring, nil
+}
+
+// Как часто нужно вызывать Reload.
+func (r *KeyRing) ReloadInterval() time.Duration {
+	return r.reloadInterval
+}
+
+// Перечитывает файл связки. При ошибке остаются ранее загруженные ключи.
+func (r *KeyRing) Reload() error {
+	if r.path == "" {
+		return nil
+	}
+
+	file, err := ReadKeyRingFile(r.path)
+	if err != nil {
+		return fmt.Errorf("hashes.KeyRing.Reload(1): %w", err)
+	}
+
+	hmacKeys := make(map[string]*hmacKey, len(file.Hmac))
+	for _, entry := range file.Hmac {
+		secret, err := base64.StdEncoding.DecodeString(entry.Secret)
+		if err != nil || len(secret) == 0 {
+			return fmt.Errorf("hashes.KeyRing.Reload(2): invalid secret of hmac key %s", entry.Id)
+		}
+		hmacKeys[entry.Id] = &hmacKey{keyMeta: entry.meta(), secret: secret}
+	}
+
+	jwtKeys := make(map[string]*JwtKey, len(file.Jwt)+len(r.configJwt))
+	for id, key := range r.configJwt {
+		jwtKeys[id] = key
+	}
+	for _, entry := range file.Jwt {
+		if _, ok := jwtKeys[entry.Id]; ok {
+			return fmt.Errorf("hashes.KeyRing.Reload(3): jwt key %s is set both in config and in key ring file", entry.Id)
+		}
+		key, err := loadJwtKey(entry, filepath.Dir(r.path))
+		if err != nil {
+			return fmt.Errorf("hashes.KeyRing.Reload(4): jwt key %s: %w", entry.Id, err)
+		}
+		jwtKeys[entry.Id] = key
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hmac, r.jwt = hmacKeys, jwtKeys
+	return nil
+}
+
+// Ключ для подписи кодов. Пока в файле нет готового hmac-ключа, подписывает SECRET_KEY;
+// новый ключ подписывает до готовности, только если SECRET_KEY погашен.
+func (r *KeyRing) hmacSigner() (*hmacKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metas := make([]keyMeta, 0, len(r.hmac))
+	for _, key := range r.hmac {
+		metas = append(metas, key.keyMeta)
+	}
+	id, pending := pickSigner(metas, r.activationDelay)
+	switch {
+	case id != "":
+		return r.hmac[id], nil
+	case len(r.hmac) == 0 || r.legacy.State != KeyRetired:
+		return &hmacKey{keyMeta: keyMeta{Id: legacyKeyId, State: KeyActive}, secret: r.legacy.secret}, nil
+	case pending != "":
+		return r.hmac[pending], nil
+	}
+	return nil, errors.New("hashes.KeyRing.hmacSigner(1): no hmac key to sign with, all keys are retired")
+}
+
+// Ключ для проверки подписи кода или jwt HS256 (погашенные ключи не возвращаются).
+func (r *KeyRing) hmacVerifier(id string) (*hmacKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id == legacyKeyId {
+		// Пока в файле нет hmac-ключей, SECRET_KEY остается основным.
+		if len(r.hmac) > 0 && r.legacy.State == KeyRetired {
+			return nil, false
+		}
+		return r.legacy, true
+	}
+
+	key, ok := r.hmac[id]
+	if !ok || key.State == KeyRetired {
+		return nil, false
+	}
+	return key, true
+}
+
+// Ключ для подписи jwt. Пока ни один асимметричный ключ не готов, jwt подписываются HS256 hmac-ключом.
+func (r *KeyRing) jwtSigner() (*JwtKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, _ := pickSigner(r.jwtSigningMetas(), r.activationDelay)
+	if id == "" {
+		return nil, false
+	}
+	return r.jwt[id], true
+}
+
+// Ключи jwt с закрытой частью. Вызывается под r.mu.
+func (r *KeyRing) jwtSigningMetas() []keyMeta {
+	metas := make([]keyMeta, 0, len(r.jwt))
+	for _, key := range r.jwt {
+		if key.private != nil {
+			metas = append(metas, key.keyMeta)
+		}
+	}
+	return metas
+}
+
+// Асимметричный ключ для проверки jwt (погашенные ключи не возвращаются).
+func (r *KeyRing) jwtVerifier(id string) (*JwtKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.jwt[id]
+	if !ok || key.State == KeyRetired {
+		return nil, false
+	}
+	return key, true
+}
+
+// Принимаются ли jwt HS256: без асимметричных ключей, пока они подписываются HS256 (новый ключ
+// еще не готов) и на время перехода (legacy_hmac).
+func (r *KeyRing) acceptHmacJwt() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.jwt) == 0 || r.legacyHmacJwt {
+		return true
+	}
+	id, _ := pickSigner(r.jwtSigningMetas(), r.activationDelay)
+	return id == ""
+}
+
+// Выбирает ключ подписи: самый новый активный ключ, добавленный раньше activationDelay назад
+// (новый ключ должны успеть загрузить все реплики). Пока новый ключ не готов, подписывает ключ,
+// переведенный ротацией в режим проверки позже всех. Если подходящего нет, возвращается только
+// pending - самый новый неготовый ключ: им подписывают, лишь когда больше нечем (нет SECRET_KEY).
+func pickSigner(keys []keyMeta, activationDelay time.Duration) (id, pending string) {
+	readyAt := time.Now().Add(-activationDelay)
+
+	var ready, notReady, previous *keyMeta
+	for i := range keys {
+		key := &keys[i]
+		switch {
+		case key.State == KeyActive && !key.CreatedAt.After(readyAt):
+			if ready == nil || key.CreatedAt.After(ready.CreatedAt) {
+				ready = key
+			}
+		case key.State == KeyActive:
+			if notReady == nil || key.CreatedAt.After(notReady.CreatedAt) {
+				notReady = key
+			}
+		case key.State == KeyVerifyOnly && key.RotatedAt != nil && key.RotatedAt.After(readyAt):
+			if previous == nil || key.RotatedAt.After(*previous.RotatedAt) {
+				previous = key
+			}
+		}
+	}
+
+	if notReady != nil {
+		pending = notReady.Id
+	}
+	for _, key := range []*keyMeta{ready, previous} {
+		if key != nil {
+			return key.Id, pending
+		}
+	}
+	return "", pending
+}
+
+// Загружает ключи jwt из конфига (jwt.keys). Ключ подписи считается активным с нулевым временем создания:
+// он готов сразу, а первый готовый активный ключ из файла связки, как более новый, его сменяет.
+// Остальные ключи конфига только проверяют токены.
+func loadConfigJwtKeys(cfg *config.Jwt) (map[string]*JwtKey, error) {
+	signingKey := cfg.SigningKey
+	keys := make(map[string]*JwtKey, len(cfg.Keys))
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.Id == "" {
+			return nil, errors.New("hashes.loadConfigJwtKeys(1): key id is empty")
+		}
+		if _, ok := keys[keyCfg.Id]; ok {
+			return nil, fmt.Errorf("hashes.loadConfigJwtKeys(2): duplicate key id %s", keyCfg.Id)
+		}
+
+		entry := &KeyRingEntry{Id: keyCfg.Id, State: KeyVerifyOnly, PrivateKey: keyCfg.PrivateKey, PublicKey: keyCfg.PublicKey}
+		key, err := loadJwtKey(entry, ".")
+		if err != nil {
+			return nil, fmt.Errorf("hashes.loadConfigJwtKeys(3): key %s: %w", keyCfg.Id, err)
+		}
+		keys[key.Id] = key
+
+		if signingKey == "" && key.private != nil {
+			signingKey = key.Id
+		}
+	}
+	if len(keys) == 0 {
+		return keys, nil
+	}
+
+	key, ok := keys[signingKey]
+	if !ok || key.private == nil {
+		return nil, fmt.Errorf("hashes.loadConfigJwtKeys(4): signing key %s not found or has no private key", signingKey)
+	}
+	key.State = KeyActive
+	return keys, nil
+}
+
+func (e *KeyRingEntry) meta() keyMeta {
+	return keyMeta{Id: e.Id, State: e.State, CreatedAt: e.CreatedAt, RotatedAt: e.RotatedAt}
+}
+
+func ReadKeyRingFile(path string) (*KeyRingFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.ReadKeyRingFile(1): %w", err)
+	}
+
+	file := new(KeyRingFile)
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("hashes.ReadKeyRingFile(2): %w", err)
+	}
+	if err := file.validate(); err != nil {
+		return nil, fmt.Errorf("hashes.ReadKeyRingFile(3): %w", err)
+	}
+	return file, nil
+}
+
+// Записывает файл связки атомарно (через временный файл), чтобы сервер не прочитал его наполовину.
+func WriteKeyRingFile(path string, file *KeyRingFile) error {
+	if err := file.validate(); err != nil {
+		return fmt.Errorf("hashes.WriteKeyRingFile(1): %w", err)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("hashes.WriteKeyRingFile(2): %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("hashes.WriteKeyRingFile(3): %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("hashes.WriteKeyRingFile(4): %w", err)
+	}
+	return nil
+}
+
+// Добавляет новый активный ключ, а прежние активные переводит в режим проверки. Пока новый ключ
+// не разойдется по репликам (activation_delay), подписывать продолжает прежний ключ, а при первой
+// ротации - SECRET_KEY (см. pickSigner).
+// Ключи в режиме проверки, переставшие подписывать раньше maxAge назад, гасятся:
+// все подписанные ими токены и коды к этому времени истекли.
+func (f *KeyRingFile) Rotate(kind string, entry *KeyRingEntry, maxAge time.Duration) error {
+	entries, err := f.entries(kind)
+	if err != nil {
+		return fmt.Errorf("hashes.KeyRingFile.Rotate(1): %w", err)
+	}
+	for _, existing := range *entries {
+		if existing.Id == entry.Id {
+			return fmt.Errorf("hashes.KeyRingFile.Rotate(2): duplicate key id %s", entry.Id)
+		}
+	}
+
+	now := time.Now()
+	f.retireExpired(kind, now.Add(-maxAge))
+
+	for _, existing := range *entries {
+		if existing.State == KeyActive {
+			existing.State = KeyVerifyOnly
+			existing.RotatedAt = &now
+		}
+	}
+
+	entry.State = KeyActive
+	entry.CreatedAt = now
+	*entries = append(*entries, entry)
+	return nil
+}
+
+// Гасит ключ вручную (например, при утечке). Активный ключ погасить нельзя: сначала нужна ротация.
+func (f *KeyRingFile) Retire(kind, id string) error {
+	entries, err := f.entries(kind)
+	if err != nil {
+		return fmt.Errorf("hashes.KeyRingFile.Retire(1): %w", err)
+	}
+
+	for _, entry := range *entries {
+		if entry.Id != id {
+			continue
+		}
+		if entry.State == KeyActive {
+			return fmt.Errorf("hashes.KeyRingFile.Retire(2): key %s is active, rotate it first", id)
+		}
+		entry.State = KeyRetired
+		return nil
+	}
+	return fmt.Errorf("hashes.KeyRingFile.Retire(3): key %s not found", id)
+}
+
+// Гасит ключи в режиме проверки, переставшие подписывать раньше before.
+func (f *KeyRingFile) retireExpired(kind string, before time.Time) {
+	entries, err := f.entries(kind)
+	if err != nil {
+		return
+	}
+	for _, entry := range *entries {
+		if entry.State == KeyVerifyOnly && entry.RotatedAt != nil && entry.RotatedAt.Before(before) {
+			entry.State = KeyRetired
+		}
+	}
+}
+
+func (f *KeyRingFile) entries(kind string) (*[]*KeyRingEntry, error) {
+	switch kind {
+	case KeyKindHmac:
+		return &f.Hmac, nil
+	case KeyKindJwt:
+		return &f.Jwt, nil
+	}
+	return nil, fmt.Errorf("hashes.KeyRingFile.entries(1): unknown key kind %s", kind)
+}
+
+func (f *KeyRingFile) validate() error {
+	for _, entries := range [][]*KeyRingEntry{f.Hmac, f.Jwt} {
+		ids := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			if entry.Id == "" {
+				return errors.New("hashes.KeyRingFile.validate(1): key id is empty")
+			}
+			if ids[entry.Id] {
+				return fmt.Errorf("hashes.KeyRingFile.validate(2): duplicate key id %s", entry.Id)
+			}
+			ids[entry.Id] = true
+
+			switch entry.State {
+			case KeyActive, KeyVerifyOnly, KeyRetired:
+			default:
+				return fmt.Errorf("hashes.KeyRingFile.validate(3): invalid state %s of key %s", entry.State, entry.Id)
+			}
+		}
+	}
+	return nil
+}
+
+// Генерирует id ключа: вид, дата и случайный суффикс (например, hmac-20240131-1a2b3c).
+func GenKeyId(kind string) (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("hashes.GenKeyId(1): %w", err)
+	}
+	return fmt.Sprintf("%s-%s-%s", kind, time.Now().UTC().Format("20060102"), hex.EncodeToString(suffix)), nil
+}
+
+// Генерирует новый hmac-ключ для связки.
+func GenHmacKeyEntry() (*KeyRingEntry, error) {
+	id, err := GenKeyId(KeyKindHmac)
+	if err != nil {
+		return nil, fmt.Errorf("hashes.GenHmacKeyEntry(1): %w", err)
+	}
+
+	secret := make([]byte, hmacKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("hashes.GenHmacKeyEntry(2): %w", err)
+	}
+	return &KeyRingEntry{Id: id, Secret: base64.StdEncoding.EncodeToString(secret)}, nil
+}
@@ -0,0 +1,200 @@
+package hashes
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lesienchik/vk__test/internal/config"
+)
+
+func TestPickSigner(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	now := time.Now()
+	rotatedAt := now.Add(-time.Minute)
+	oldRotatedAt := now.Add(-time.Hour)
+
+	testTable := []struct {
+		desc     string    // Описание теста
+		input    []keyMeta // Входные данные
+		expected [2]string // Ожидаемые id готового ключа подписи и неготового ключа
+	}{
+		{
+			desc:     "No keys",
+			input:    nil,
+			expected: [2]string{"", ""},
+		},
+		{
+			desc: "Newest ready key",
+			input: []keyMeta{
+				{Id: "k1", State: KeyActive, CreatedAt: now.Add(-2 * time.Hour)},
+				{Id: "k2", State: KeyActive, CreatedAt: now.Add(-time.Hour)},
+			},
+			expected: [2]string{"k2", ""},
+		},
+		{
+			desc: "First key pending",
+			input: []keyMeta{
+				{Id: "k1", State: KeyActive, CreatedAt: now},
+			},
+			expected: [2]string{"", "k1"},
+		},
+		{
+			desc: "New key pending, previous key signs",
+			input: []keyMeta{
+				{Id: "k1", State: KeyVerifyOnly, CreatedAt: now.Add(-time.Hour), RotatedAt: &rotatedAt},
+				{Id: "k2", State: KeyActive, CreatedAt: rotatedAt},
+			},
+			expected: [2]string{"k1", "k2"},
+		},
+		{
+			desc: "Previous key long rotated out, new key pending",
+			input: []keyMeta{
+				{Id: "k1", State: KeyVerifyOnly, CreatedAt: now.Add(-2 * time.Hour), RotatedAt: &oldRotatedAt},
+				{Id: "k2", State: KeyActive, CreatedAt: now},
+			},
+			expected: [2]string{"", "k2"},
+		},
+		{
+			desc: "Retired keys do not sign",
+			input: []keyMeta{
+				{Id: "k1", State: KeyRetired, CreatedAt: now.Add(-time.Hour), RotatedAt: &rotatedAt},
+			},
+			expected: [2]string{"", ""},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			// Act
+			id, pending := pickSigner(tc.input, 5*time.Minute)
+
+			// Assert
+			requires.Equal(tc.expected, [2]string{id, pending})
+		})
+	}
+}
+
+func TestKeyRingHmacSigner(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	testTable := []struct {
+		desc     string   // Описание теста
+		state    KeyState // Состояние hmac-ключа в файле
+		legacy   KeyState // Состояние SECRET_KEY
+		expected string   // Ожидаемый id ключа подписи ("-" - ошибка)
+	}{
+		{
+			desc:     "First key pending, SECRET_KEY signs",
+			state:    KeyActive,
+			legacy:   KeyVerifyOnly,
+			expected: legacyKeyId,
+		},
+		{
+			desc:     "First key pending, SECRET_KEY retired",
+			state:    KeyActive,
+			legacy:   KeyRetired,
+			expected: "k1",
+		},
+		{
+			desc:     "All keys retired",
+			state:    KeyRetired,
+			legacy:   KeyRetired,
+			expected: "-",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			entry, err := GenHmacKeyEntry()
+			requires.NoError(err)
+			entry.Id, entry.State, entry.CreatedAt = "k1", tc.state, time.Now()
+
+			path := filepath.Join(t.TempDir(), "keyring.json")
+			requires.NoError(WriteKeyRingFile(path, &KeyRingFile{Hmac: []*KeyRingEntry{entry}}))
+			ring, err := NewKeyRing(&config.KeyRing{Path: path, LegacySecret: string(tc.legacy)}, &config.Jwt{}, "secret")
+			requires.NoError(err)
+
+			// Act
+			key, err := ring.hmacSigner()
+
+			// Assert
+			if tc.expected == "-" {
+				requires.Error(err)
+				_, err = HmacGenHash("data", time.Minute, ring)
+				requires.Error(err)
+				return
+			}
+			requires.NoError(err)
+			requires.Equal(tc.expected, key.Id)
+		})
+	}
+}
+
+func TestKeyRingFileRotate(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	expiredAt := time.Now().Add(-2 * time.Hour)
+	file := &KeyRingFile{Hmac: []*KeyRingEntry{
+		{Id: "k1", State: KeyVerifyOnly, RotatedAt: &expiredAt},
+		{Id: "k2", State: KeyActive},
+	}}
+
+	// Act
+	err := file.Rotate(KeyKindHmac, &KeyRingEntry{Id: "k3"}, time.Hour)
+	dupErr := file.Rotate(KeyKindHmac, &KeyRingEntry{Id: "k3"}, time.Hour)
+	activeErr := file.Retire(KeyKindHmac, "k3")
+
+	// Assert
+	requires.NoError(err)
+	requires.Error(dupErr)
+	requires.Error(activeErr)
+	requires.Equal(KeyRetired, file.Hmac[0].State)
+	requires.Equal(KeyVerifyOnly, file.Hmac[1].State)
+	requires.NotNil(file.Hmac[1].RotatedAt)
+	requires.Equal(KeyActive, file.Hmac[2].State)
+}
+
+func TestKeyRingHmacHash(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	legacyRing, err := NewKeyRing(&config.KeyRing{}, &config.Jwt{}, "secret")
+	requires.NoError(err)
+	legacyHash, err := HmacGenHash("data", time.Minute, legacyRing)
+	requires.NoError(err)
+
+	entry, err := GenHmacKeyEntry()
+	requires.NoError(err)
+	entry.State, entry.CreatedAt = KeyActive, time.Now().Add(-time.Hour)
+	file := &KeyRingFile{Hmac: []*KeyRingEntry{entry}}
+	ring := newTestKeyRing(t, file, &config.Jwt{})
+
+	// Act
+	hash, err := HmacGenHash("data", time.Minute, ring)
+	requires.NoError(err)
+
+	// Assert
+	requires.Len(strings.Split(legacyHash, "."), 2)
+	requires.True(strings.HasPrefix(hash, entry.Id+"."))
+
+	var out string
+	for _, h := range []string{hash, legacyHash} {
+		status, err := HmacParseAndValidateHash(h, &out, ring)
+		requires.NoError(err)
+		requires.Equal(HashValid, status)
+		requires.Equal("data", out)
+	}
+
+	retiredRing, err := NewKeyRing(&config.KeyRing{Path: ring.path, LegacySecret: string(KeyRetired)}, &config.Jwt{}, "secret")
+	requires.NoError(err)
+	_, err = HmacParseAndValidateHash(legacyHash, &out, retiredRing)
+	requires.Error(err)
+}
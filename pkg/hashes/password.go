@@ -0,0 +1,356 @@
+package hashes
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/lesienchik/vk__test/internal/config"
+)
+
+// Идентификаторы алгоритмов хэширования паролей (совпадают с префиксом хэша в формате PHC).
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Параметры по умолчанию (рекомендации OWASP).
+const (
+	defaultArgon2Memory      = 19 * 1024 // КиБ
+	defaultArgon2Iterations  = 2
+	defaultArgon2Parallelism = 1
+	argon2SaltSize           = 16
+	argon2KeySize            = 32
+	pepperPrefix             = "$pepper$v=" // Префикс хэша с перцем: $pepper$v=<версия>$<хэш алгоритма>
+)
+
+// Допустимые границы параметров argon2id. Ограничения сверху не дают поддельному хэшу
+// или ошибке в конфиге заставить сервер тратить на проверку пароля неограниченные ресурсы.
+const (
+	maxArgon2Memory      = 1024 * 1024 // КиБ (1 ГиБ)
+	maxArgon2Iterations  = 16
+	maxArgon2Parallelism = 16
+	maxArgon2KeySize     = 64
+)
+
+// Алгоритм хэширования паролей.
+type Hasher interface {
+	// Хэширует пароль с текущими параметрами.
+	Hash(password string) (string, error)
+	// Проверяет пароль по хэшу этого алгоритма.
+	Verify(encoded, password string) error
+	// Сообщает, что хэш создан с параметрами, отличными от текущих.
+	NeedsRehash(encoded string) bool
+	// Проверяет, что хэш создан этим алгоритмом.
+	Owns(encoded string) bool
+}
+
+// Хэширует пароли текущим алгоритмом и проверяет хэши всех поддерживаемых алгоритмов.
+// Это позволяет менять алгоритм и повышать его стоимость без сброса паролей пользователей.
+// Если задан перец, пароль перед хэшированием заменяется на HMAC от него: без секрета сервера
+// утекшая таблица users не позволяет подбирать пароли.
+type PasswordHasher struct {
+	current       Hasher
+	hashers       []Hasher
+	peppers       map[int][]byte // Версия -> секрет
+	pepperVersion int            // Версия перца для новых хэшей (0 - без перца)
+}
+
+func NewPasswordHasher(cfg *config.Password) (*PasswordHasher, error) {
+	memory, iterations, parallelism := cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism
+	if memory == 0 {
+		memory = defaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = defaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = defaultArgon2Parallelism
+	}
+	// Проверяем значения до приведения к беззнаковым типам, иначе отрицательные и большие числа переполнятся.
+	if err := validateArgon2Params(memory, iterations, parallelism); err != nil {
+		return nil, fmt.Errorf("hashes.NewPasswordHasher(1): %w", err)
+	}
+	argon2id := &argon2idHasher{
+		memory:      uint32(memory),
+		iterations:  uint32(iterations),
+		parallelism: uint8(parallelism),
+	}
+
+	bcryptCost := cfg.BcryptCost
+	if bcryptCost == 0 {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("hashes.NewPasswordHasher(2): invalid bcrypt cost %d", bcryptCost)
+	}
+	bcryptHasher := &bcryptHasher{cost: bcryptCost}
+
+	hasher := &PasswordHasher{hashers: []Hasher{argon2id, bcryptHasher}, peppers: make(map[int][]byte)}
+	switch cfg.Algorithm {
+	case AlgorithmArgon2id, "":
+		hasher.current = argon2id
+	case AlgorithmBcrypt:
+		hasher.current = bcryptHasher
+	default:
+		return nil, fmt.Errorf("hashes.NewPasswordHasher(3): unknown algorithm %s", cfg.Algorithm)
+	}
+
+	for _, pepper := range cfg.Peppers {
+		rawVersion, secret, ok := strings.Cut(strings.TrimSpace(pepper), ":")
+		version, err := strconv.Atoi(rawVersion)
+		if !ok || err != nil || version <= 0 || secret == "" {
+			return nil, errors.New("hashes.NewPasswordHasher(4): pepper must be in <version>:<secret> format")
+		}
+		hasher.peppers[version] = []byte(secret)
+		hasher.pepperVersion = max(hasher.pepperVersion, version)
+	}
+	if cfg.PepperVersion != 0 {
+		if _, ok := hasher.peppers[cfg.PepperVersion]; !ok {
+			return nil, fmt.Errorf("hashes.NewPasswordHasher(5): pepper version %d is not set", cfg.PepperVersion)
+		}
+		hasher.pepperVersion = cfg.PepperVersion
+	}
+	return hasher, nil
+}
+
+// Хэширует пароль текущим алгоритмом.
+func (p *PasswordHasher) Hash(password string) (string, error) {
+	if len(password) == 0 {
+		return "", errors.New("hashes.PasswordHasher.Hash(1): password is empty")
+	}
+
+	peppered, err := p.pepper(password, p.pepperVersion)
+	if err != nil {
+		return "", fmt.Errorf("hashes.PasswordHasher.Hash(2): %w", err)
+	}
+
+	hash, err := p.current.Hash(peppered)
+	if err != nil {
+		return "", fmt.Errorf("hashes.PasswordHasher.Hash(3): %w", err)
+	}
+	if p.pepperVersion == 0 {
+		return hash, nil
+	}
+	return pepperPrefix + strconv.Itoa(p.pepperVersion) + hash, nil
+}
+
+// Проверяет пароль. Возвращает true, если пароль верен, но хэш стоит пересчитать
+// (создан другим алгоритмом, с устаревшими параметрами или с другой версией перца).
+func (p *PasswordHasher) Verify(encoded, password string) (bool, error) {
+	if len(encoded) == 0 {
+		return false, errors.New("hashes.PasswordHasher.Verify(1): hashedPassword is empty")
+	}
+	if len(password) == 0 {
+		return false, errors.New("hashes.PasswordHasher.Verify(2): password is empty")
+	}
+
+	version, encoded, err := splitPepperVersion(encoded)
+	if err != nil {
+		return false, fmt.Errorf("hashes.PasswordHasher.Verify(3): %w", err)
+	}
+	peppered, err := p.pepper(password, version)
+	if err != nil {
+		return false, fmt.Errorf("hashes.PasswordHasher.Verify(4): %w", err)
+	}
+
+	for _, hasher := range p.hashers {
+		if !hasher.Owns(encoded) {
+			continue
+		}
+		if err := hasher.Verify(encoded, peppered); err != nil {
+			return false, fmt.Errorf("hashes.PasswordHasher.Verify(5): %w", err)
+		}
+		return version != p.pepperVersion || hasher != p.current || hasher.NeedsRehash(encoded), nil
+	}
+	return false, errors.New("hashes.PasswordHasher.Verify(6): unknown hash format")
+}
+
+// Смешивает пароль с перцем указанной версии (0 - пароль не меняется).
+// HMAC кодируется в base64, чтобы результат не содержал нулевых байт и укладывался в ограничение bcrypt.
+func (p *PasswordHasher) pepper(password string, version int) (string, error) {
+	if version == 0 {
+		return password, nil
+	}
+
+	secret, ok := p.peppers[version]
+	if !ok {
+		return "", fmt.Errorf("hashes.PasswordHasher.pepper(1): pepper version %d is retired", version)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Отделяет версию перца от хэша алгоритма. Для хэшей без перца возвращает версию 0.
+func splitPepperVersion(encoded string) (int, string, error) {
+	if !strings.HasPrefix(encoded, pepperPrefix) {
+		return 0, encoded, nil
+	}
+
+	rest := strings.TrimPrefix(encoded, pepperPrefix)
+	end := strings.Index(rest, "$")
+	if end <= 0 {
+		return 0, "", errors.New("hashes.splitPepperVersion(1): invalid format hash")
+	}
+
+	version, err := strconv.Atoi(rest[:end])
+	if err != nil || version <= 0 {
+		return 0, "", errors.New("hashes.splitPepperVersion(2): invalid pepper version")
+	}
+	return version, rest[end:], nil
+}
+
+// argon2id, хэш в формате PHC: $argon2id$v=19$m=<память КиБ>,t=<итерации>,p=<потоки>$<соль>$<хэш>.
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// Разобранный PHC-хэш argon2id.
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a *argon2idHasher) Owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$")
+}
+
+func (a *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("hashes.argon2idHasher.Hash(1): %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.iterations, a.memory, a.parallelism, argon2KeySize)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id, argon2.Version, a.memory, a.iterations, a.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *argon2idHasher) Verify(encoded, password string) error {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return fmt.Errorf("hashes.argon2idHasher.Verify(1): %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return errors.New("hashes.argon2idHasher.Verify(2): password does not match")
+	}
+	return nil
+}
+
+func (a *argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory != a.memory || params.iterations != a.iterations || params.parallelism != a.parallelism ||
+		len(params.key) != argon2KeySize
+}
+
+func parseArgon2id(encoded string) (*argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", соль, хэш
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, errors.New("hashes.parseArgon2id(1): invalid format hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("hashes.parseArgon2id(2): %w", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("hashes.parseArgon2id(3): unsupported version %d", version)
+	}
+
+	var memory, iterations, parallelism int
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return nil, fmt.Errorf("hashes.parseArgon2id(4): %w", err)
+	}
+	if err := validateArgon2Params(memory, iterations, parallelism); err != nil {
+		return nil, fmt.Errorf("hashes.parseArgon2id(5): %w", err)
+	}
+	params := &argon2idParams{
+		memory:      uint32(memory),
+		iterations:  uint32(iterations),
+		parallelism: uint8(parallelism),
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("hashes.parseArgon2id(6): %w", err)
+	}
+	if len(params.salt) == 0 {
+		return nil, errors.New("hashes.parseArgon2id(7): salt is empty")
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("hashes.parseArgon2id(8): %w", err)
+	}
+	if len(params.key) == 0 || len(params.key) > maxArgon2KeySize {
+		return nil, fmt.Errorf("hashes.parseArgon2id(9): invalid key size %d", len(params.key))
+	}
+	return params, nil
+}
+
+// Проверяет, что параметры argon2id лежат в допустимых границах.
+// Память не может быть меньше 8 КиБ на поток (требование алгоритма).
+func validateArgon2Params(memory, iterations, parallelism int) error {
+	if parallelism < 1 || parallelism > maxArgon2Parallelism {
+		return fmt.Errorf("hashes.validateArgon2Params(1): invalid parallelism %d", parallelism)
+	}
+	if memory < 8*parallelism || memory > maxArgon2Memory {
+		return fmt.Errorf("hashes.validateArgon2Params(2): invalid memory %d", memory)
+	}
+	if iterations < 1 || iterations > maxArgon2Iterations {
+		return fmt.Errorf("hashes.validateArgon2Params(3): invalid iterations %d", iterations)
+	}
+	return nil
+}
+
+// bcrypt, хэш в стандартном формате: $2a$<стоимость>$<соль и хэш>.
+type bcryptHasher struct {
+	cost int
+}
+
+func (b *bcryptHasher) Owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", fmt.Errorf("hashes.bcryptHasher.Hash(1): %w", err)
+	}
+	return string(hash), nil
+}
+
+func (b *bcryptHasher) Verify(encoded, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+		return fmt.Errorf("hashes.bcryptHasher.Verify(1): %w", err)
+	}
+	return nil
+}
+
+func (b *bcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.cost
+}
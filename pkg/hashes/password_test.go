@@ -0,0 +1,278 @@
+package hashes
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/lesienchik/vk__test/internal/config"
+)
+
+func TestPasswordHasherHash(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	testTable := []struct {
+		desc     string          // Описание теста
+		input    config.Password // Входные данные
+		expected string          // Ожидаемый префикс хэша
+	}{
+		{
+			desc:     "Default argon2id",
+			input:    config.Password{}, // По умолчанию argon2id
+			expected: "$argon2id$v=19$m=19456,t=2,p=1$",
+		},
+		{
+			desc:     "Custom argon2id params",
+			input:    config.Password{Algorithm: AlgorithmArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 2},
+			expected: "$argon2id$v=19$m=1024,t=1,p=2$",
+		},
+		{
+			desc:     "Bcrypt",
+			input:    config.Password{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost},
+			expected: "$2a$04$",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			// Act
+			hasher, err := NewPasswordHasher(&tc.input)
+			requires.NoError(err)
+
+			hash, err := hasher.Hash("Password1")
+			requires.NoError(err)
+
+			// Assert
+			requires.True(strings.HasPrefix(hash, tc.expected), hash)
+
+			rehash, err := hasher.Verify(hash, "Password1")
+			requires.NoError(err)
+			requires.False(rehash)
+
+			_, err = hasher.Verify(hash, "Password2")
+			requires.Error(err)
+		})
+	}
+}
+
+func TestPasswordHasherRehash(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	oldBcrypt, err := NewPasswordHasher(&config.Password{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	requires.NoError(err)
+	oldArgon2id, err := NewPasswordHasher(&config.Password{Argon2Memory: 1024, Argon2Iterations: 1})
+	requires.NoError(err)
+	current, err := NewPasswordHasher(&config.Password{Argon2Memory: 2048, Argon2Iterations: 1})
+	requires.NoError(err)
+
+	testTable := []struct {
+		desc     string          // Описание теста
+		input    *PasswordHasher // Хэшер, которым создан старый хэш
+		expected bool            // Ожидаемый результат выполнения теста
+	}{
+		{
+			desc:     "Other algorithm",
+			input:    oldBcrypt,
+			expected: true,
+		},
+		{
+			desc:     "Old params",
+			input:    oldArgon2id,
+			expected: true,
+		},
+		{
+			desc:     "Current params",
+			input:    current,
+			expected: false,
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			hash, err := tc.input.Hash("Password1")
+			requires.NoError(err)
+
+			// Act
+			rehash, err := current.Verify(hash, "Password1")
+
+			// Assert
+			requires.NoError(err)
+			requires.Equal(tc.expected, rehash)
+		})
+	}
+}
+
+func TestNewPasswordHasherInvalid(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	testTable := []struct {
+		desc  string          // Описание теста
+		input config.Password // Входные данные
+	}{
+		{
+			desc:  "Unknown algorithm",
+			input: config.Password{Algorithm: "md5"},
+		},
+		{
+			desc:  "Bcrypt cost too high",
+			input: config.Password{Algorithm: AlgorithmBcrypt, BcryptCost: 100},
+		},
+		{
+			desc:  "Negative memory",
+			input: config.Password{Argon2Memory: -1},
+		},
+		{
+			desc:  "Memory overflows uint32",
+			input: config.Password{Argon2Memory: 1 << 32},
+		},
+		{
+			desc:  "Negative iterations",
+			input: config.Password{Argon2Iterations: -1},
+		},
+		{
+			desc:  "Too many iterations",
+			input: config.Password{Argon2Iterations: 1000},
+		},
+		{
+			desc:  "Parallelism overflows uint8",
+			input: config.Password{Argon2Parallelism: 256},
+		},
+		{
+			desc:  "Memory less than 8 KiB per thread",
+			input: config.Password{Argon2Memory: 8, Argon2Parallelism: 2},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			// Act
+			_, err := NewPasswordHasher(&tc.input)
+
+			// Assert
+			requires.Error(err)
+		})
+	}
+}
+
+func TestPasswordHasherVerifyMalformed(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	hasher, err := NewPasswordHasher(&config.Password{})
+	requires.NoError(err)
+
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	testTable := []struct {
+		desc  string // Описание теста
+		input string // Хэш
+	}{
+		{
+			desc:  "Missing parts",
+			input: "$argon2id$v=19$m=1024,t=1,p=1$" + salt,
+		},
+		{
+			desc:  "Unsupported version",
+			input: "$argon2id$v=16$m=1024,t=1,p=1$" + salt + "$" + key,
+		},
+		{
+			desc:  "Zero memory",
+			input: "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key,
+		},
+		{
+			desc:  "Oversized memory",
+			input: "$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key,
+		},
+		{
+			desc:  "Zero iterations",
+			input: "$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + key,
+		},
+		{
+			desc:  "Oversized iterations",
+			input: "$argon2id$v=19$m=1024,t=100000,p=1$" + salt + "$" + key,
+		},
+		{
+			desc:  "Zero parallelism",
+			input: "$argon2id$v=19$m=1024,t=1,p=0$" + salt + "$" + key,
+		},
+		{
+			desc:  "Oversized parallelism",
+			input: "$argon2id$v=19$m=1024,t=1,p=300$" + salt + "$" + key,
+		},
+		{
+			desc:  "Empty salt",
+			input: "$argon2id$v=19$m=1024,t=1,p=1$$" + key,
+		},
+		{
+			desc:  "Empty key",
+			input: "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$",
+		},
+		{
+			desc:  "Invalid base64",
+			input: "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$!!!",
+		},
+		{
+			desc:  "Unknown format",
+			input: "$md5$" + key,
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.desc, func(t *testing.T) {
+			// Act
+			rehash, err := hasher.Verify(tc.input, "Password1")
+
+			// Assert
+			requires.Error(err)
+			requires.False(rehash)
+		})
+	}
+}
+
+func TestPasswordHasherPepper(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	params := config.Password{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+
+	withoutPepper, err := NewPasswordHasher(&params)
+	requires.NoError(err)
+
+	params.Peppers = []string{"1:first"}
+	firstPepper, err := NewPasswordHasher(&params)
+	requires.NoError(err)
+
+	params.Peppers = []string{"1:first", "2:second"}
+	rotated, err := NewPasswordHasher(&params)
+	requires.NoError(err)
+
+	params.Peppers = []string{"2:second"}
+	retired, err := NewPasswordHasher(&params)
+	requires.NoError(err)
+
+	plainHash, err := withoutPepper.Hash("Password1")
+	requires.NoError(err)
+	firstHash, err := firstPepper.Hash("Password1")
+	requires.NoError(err)
+
+	// Act
+	plainRehash, plainErr := rotated.Verify(plainHash, "Password1")
+	firstRehash, firstErr := rotated.Verify(firstHash, "Password1")
+	_, retiredErr := retired.Verify(firstHash, "Password1")
+	_, bareErr := withoutPepper.Verify(firstHash, "Password1")
+
+	// Assert
+	requires.True(strings.HasPrefix(firstHash, "$pepper$v=1$2a$"), firstHash)
+	requires.NoError(plainErr)
+	requires.True(plainRehash)
+	requires.NoError(firstErr)
+	requires.True(firstRehash)
+	requires.Error(retiredErr) // Версия перца выведена из оборота
+	requires.Error(bareErr)    // Без секрета сервера хэш не проверить
+}
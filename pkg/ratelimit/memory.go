@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Интервал, с которым из памяти удаляются неактуальные счетчики.
+const memorySweepInterval = time.Minute
+
+type memoryCounter struct {
+	start  time.Time // Начало текущего окна
+	window time.Duration
+	prev   int
+	curr   int
+}
+
+// Хранит счетчики в памяти процесса. Лимит соблюдается только в рамках одной реплики.
+type Memory struct {
+	mu        sync.Mutex
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+	now       func() time.Time // Источник текущего времени (подменяется в тестах)
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		counters:  make(map[string]*memoryCounter),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (m *Memory) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	start := windowStart(now, window)
+	m.sweep(now)
+
+	counter, ok := m.counters[key]
+	if !ok {
+		counter = &memoryCounter{start: start, window: window}
+		m.counters[key] = counter
+	}
+
+	// Сдвигаем окна, если текущее уже закончилось.
+	switch {
+	case counter.start.Equal(start):
+	case counter.start.Add(window).Equal(start):
+		counter.prev, counter.curr = counter.curr, 0
+		counter.start = start
+	default:
+		counter.prev, counter.curr = 0, 0
+		counter.start = start
+	}
+
+	allowed, retryAfter := slidingWindow(counter.prev, counter.curr+1, limit, now.Sub(start), window)
+	if allowed {
+		counter.curr++
+	}
+	return allowed, retryAfter, nil
+}
+
+// Удаляет счетчики, которые уже не влияют на оценку (старше двух окон).
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, counter := range m.counters {
+		if now.Sub(counter.start) >= 2*counter.window {
+			delete(m.counters, key)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Хранит счетчики в Postgres (таблица rate_limits). Лимит общий для всех реплик.
+type Postgres struct {
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{
+		db: db,
+	}
+}
+
+func (p *Postgres) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	start := windowStart(now, window)
+	prevStart := start.Add(-window)
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return false, 0, fmt.Errorf("ratelimit.Postgres.Allow(1): %w", err)
+	}
+	defer tx.Rollback()
+
+	// Строка текущего окна блокируется до конца транзакции: параллельные запросы по ключу выполняются по очереди.
+	lockQuery := `
+		INSERT INTO rate_limits (key, window_start, count)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (key, window_start) DO UPDATE
+		SET count = rate_limits.count
+		RETURNING count
+	`
+
+	var curr int
+	if err := tx.QueryRow(lockQuery, key, start).Scan(&curr); err != nil {
+		return false, 0, fmt.Errorf("ratelimit.Postgres.Allow(2): %w", err)
+	}
+
+	var prev int
+	prevQuery := `SELECT count FROM rate_limits WHERE key = $1 AND window_start = $2`
+	if err := tx.QueryRow(prevQuery, key, prevStart).Scan(&prev); err != nil && err != sql.ErrNoRows {
+		return false, 0, fmt.Errorf("ratelimit.Postgres.Allow(3): %w", err)
+	}
+
+	// Более старые окна на оценку уже не влияют.
+	if _, err := tx.Exec(`DELETE FROM rate_limits WHERE key = $1 AND window_start < $2`, key, prevStart); err != nil {
+		return false, 0, fmt.Errorf("ratelimit.Postgres.Allow(4): %w", err)
+	}
+
+	// Учитываются только пропущенные запросы.
+	allowed, retryAfter := slidingWindow(prev, curr+1, limit, now.Sub(start), window)
+	if allowed {
+		incQuery := `UPDATE rate_limits SET count = count + 1 WHERE key = $1 AND window_start = $2`
+		if _, err := tx.Exec(incQuery, key, start); err != nil {
+			return false, 0, fmt.Errorf("ratelimit.Postgres.Allow(5): %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("ratelimit.Postgres.Allow(6): %w", err)
+	}
+	return allowed, retryAfter, nil
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Ограничитель частоты запросов по алгоритму скользящего окна (sliding window counter):
+// число запросов оценивается как взвешенная сумма счетчиков предыдущего и текущего фиксированных окон.
+// Реализации отличаются только местом хранения счетчиков: в памяти процесса (Memory)
+// или в Postgres (Postgres), чтобы несколько реплик соблюдали общий лимит.
+type Limiter interface {
+	// Сообщает, укладывается ли запрос по ключу key в limit запросов за window, и учитывает его, если укладывается.
+	// Отклоненные запросы не учитываются, чтобы повторы не продлевали ограничение. Если запрос отклонен,
+	// возвращает, через сколько его можно повторить.
+	Allow(key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+// Начало фиксированного окна, в которое попадает момент now.
+func windowStart(now time.Time, window time.Duration) time.Time {
+	return now.Truncate(window)
+}
+
+// Оценивает число запросов в скользящем окне и время до следующего разрешенного запроса.
+// prev и curr - счетчики предыдущего и текущего фиксированных окон (curr уже включает текущий запрос),
+// elapsed - сколько прошло с начала текущего окна.
+func slidingWindow(prev, curr, limit int, elapsed, window time.Duration) (bool, time.Duration) {
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := float64(prev)*weight + float64(curr)
+	if estimate <= float64(limit) {
+		return true, 0
+	}
+
+	// Лимит исчерпан уже в текущем окне - ждем его окончания.
+	if curr >= limit || prev == 0 {
+		return false, window - elapsed
+	}
+
+	// Ждем, пока вклад предыдущего окна уменьшится настолько, чтобы оценка уложилась в лимит.
+	need := float64(window) * (1 - float64(limit-curr)/float64(prev))
+	retryAfter := time.Duration(math.Ceil(need)) - elapsed
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	return false, retryAfter
+}
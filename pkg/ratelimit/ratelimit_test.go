@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	testTable := []struct {
+		desc          string        // Описание теста
+		prev          int           // Счетчик предыдущего окна
+		curr          int           // Счетчик текущего окна (с учетом запроса)
+		elapsed       time.Duration // Сколько прошло с начала текущего окна
+		expected      bool          // Ожидаемый результат выполнения теста
+		expectedRetry time.Duration // Ожидаемое время до повтора
+	}{
+		{
+			desc:     "Success",
+			prev:     0,
+			curr:     10, // Ровно лимит
+			elapsed:  30 * time.Second,
+			expected: true,
+		},
+		{
+			desc:     "Success",
+			prev:     10,
+			curr:     5, // 10*0.5 + 5 = 10
+			elapsed:  30 * time.Second,
+			expected: true,
+		},
+		{
+			desc:          "Fail",
+			prev:          0,
+			curr:          11, // Лимит превышен в текущем окне - ждем его окончания
+			elapsed:       15 * time.Second,
+			expected:      false,
+			expectedRetry: 45 * time.Second,
+		},
+		{
+			desc:          "Fail",
+			prev:          10,
+			curr:          6, // 10*0.5 + 6 = 11, вклад прошлого окна должен упасть до 4 (на 36-й секунде)
+			elapsed:       30 * time.Second,
+			expected:      false,
+			expectedRetry: 6 * time.Second,
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d", number)
+
+		actual, retryAfter := slidingWindow(testCase.prev, testCase.curr, 10, testCase.elapsed, time.Minute)
+		// Assert
+		requires.Equal(testCase.expected, actual)
+		requires.Equal(testCase.expectedRetry, retryAfter)
+	}
+}
+
+func TestMemoryAllow(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	limiter := NewMemory()
+	// Время не меняется, поэтому окно не может смениться посреди теста.
+	now := windowStart(time.Now(), time.Hour).Add(30 * time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	// Action
+	for i := 0; i < 3; i++ {
+		allowed, _, err := limiter.Allow("key", 3, time.Hour)
+		// Assert
+		requires.NoError(err)
+		requires.True(allowed)
+	}
+
+	allowed, retryAfter, err := limiter.Allow("key", 3, time.Hour)
+	requires.NoError(err)
+	requires.False(allowed)
+	requires.Greater(retryAfter, time.Duration(0))
+
+	// Отклоненные запросы не учитываются: повторы не продлевают ограничение.
+	for i := 0; i < 5; i++ {
+		allowed, _, err := limiter.Allow("key", 3, time.Hour)
+		requires.NoError(err)
+		requires.False(allowed)
+	}
+	requires.Equal(3, limiter.counters["key"].curr)
+
+	// Другой ключ считается отдельно.
+	allowed, _, err = limiter.Allow("other", 3, time.Hour)
+	requires.NoError(err)
+	requires.True(allowed)
+}
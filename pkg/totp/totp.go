@@ -0,0 +1,110 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры по умолчанию из RFC 6238 (их же ожидают Google Authenticator и аналоги).
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	Skew       = 1  // Допустимое расхождение часов в шагах (в обе стороны)
+	SecretSize = 20 // Размер секрета в байтах (160 бит, как рекомендует RFC 4226)
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Генерирует случайный секрет в base32 (без паддинга).
+func GenerateSecret() (string, error) {
+	buf := make([]byte, SecretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("totp.GenerateSecret(1): %w", err)
+	}
+	return b32.EncodeToString(buf), nil
+}
+
+// Формирует otpauth:// ссылку для добавления секрета в приложение-аутентификатор (обычно в виде QR-кода).
+func URI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+// Возвращает номер временного шага для момента t.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Генерирует код для момента t.
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("totp.GenerateCode(1): %w", err)
+	}
+	return hotp(key, Step(t)), nil
+}
+
+// Проверяет код с учетом расхождения часов. Возвращает шаг, на котором код совпал,
+// чтобы вызывающая сторона могла запретить его повторное использование.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := Step(t)
+	for i := -Skew; i <= Skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("totp.decodeSecret(1): %w", err)
+	}
+	return key, nil
+}
+
+// HOTP из RFC 4226 (HMAC-SHA1 + динамическое усечение).
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
@@ -0,0 +1,129 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Тестовые векторы из приложения B RFC 6238 (SHA1, 8 цифр), приведенные к 6 цифрам.
+func TestGenerateCode(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	testTable := []struct {
+		desc     string    // Описание теста
+		input    time.Time // Входные данные
+		expected string    // Ожидаемый результат выполнения теста
+	}{
+		{
+			desc:     "Success",
+			input:    time.Unix(59, 0),
+			expected: "287082",
+		},
+		{
+			desc:     "Success",
+			input:    time.Unix(1111111109, 0),
+			expected: "081804",
+		},
+		{
+			desc:     "Success",
+			input:    time.Unix(1111111111, 0),
+			expected: "050471",
+		},
+		{
+			desc:     "Success",
+			input:    time.Unix(1234567890, 0),
+			expected: "005924",
+		},
+		{
+			desc:     "Success",
+			input:    time.Unix(2000000000, 0),
+			expected: "279037",
+		},
+		{
+			desc:     "Success",
+			input:    time.Unix(20000000000, 0),
+			expected: "353130",
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d", number)
+
+		actual, err := GenerateCode(secret, testCase.input)
+		// Assert
+		requires.NoError(err)
+		requires.Equal(testCase.expected, actual)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	now := time.Unix(1700000000, 0)
+	code, err := GenerateCode(secret, now)
+	requires.NoError(err)
+
+	testTable := []struct {
+		desc     string    // Описание теста
+		code     string    // Входной код
+		at       time.Time // Момент проверки
+		expected bool      // Ожидаемый результат выполнения теста
+	}{
+		{
+			desc:     "Success",
+			code:     code,
+			at:       now,
+			expected: true,
+		},
+		{
+			desc:     "Success",
+			code:     code,
+			at:       now.Add(Period), // Допускаем расхождение часов на один шаг
+			expected: true,
+		},
+		{
+			desc:     "Success",
+			code:     code,
+			at:       now.Add(-Period),
+			expected: true,
+		},
+		{
+			desc:     "Fail",
+			code:     code,
+			at:       now.Add(3 * Period), // Код устарел
+			expected: false,
+		},
+		{
+			desc:     "Fail",
+			code:     "12345", // Неверная длина
+			at:       now,
+			expected: false,
+		},
+		{
+			desc:     "Fail",
+			code:     "",
+			at:       now,
+			expected: false,
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d", number)
+
+		step, actual := Validate(secret, testCase.code, testCase.at)
+		// Assert
+		requires.Equal(testCase.expected, actual)
+		if actual {
+			requires.Equal(Step(now), step)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package useragent
+
+import "strings"
+
+const unknown = "Неизвестное устройство"
+
+// Признаки браузеров и систем в порядке проверки: более специфичные идут раньше общих
+// (в user agent Edge и Opera есть "Chrome", в user agent Chrome есть "Safari").
+var (
+	browsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"YaBrowser/", "Яндекс Браузер"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	systems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// Возвращает примерное описание устройства по user agent, например "Chrome, Windows".
+// Точное определение не требуется: описание нужно, чтобы пользователь узнал (или не узнал) свое устройство.
+func Describe(userAgent string) string {
+	var parts []string
+	for _, browser := range browsers {
+		if strings.Contains(userAgent, browser.token) {
+			parts = append(parts, browser.name)
+			break
+		}
+	}
+	for _, system := range systems {
+		if strings.Contains(userAgent, system.token) {
+			parts = append(parts, system.name)
+			break
+		}
+	}
+
+	if len(parts) == 0 {
+		return unknown
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,63 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	// Arrange
+	requires := require.New(t)
+
+	testTable := []struct {
+		desc     string // Описание теста
+		input    string // Входные данные
+		expected string // Ожидаемый результат выполнения теста
+	}{
+		{
+			desc:     "Chrome on Windows",
+			input:    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			expected: "Chrome, Windows",
+		},
+		{
+			desc:     "Edge on Windows", // В user agent Edge есть и Chrome, и Safari
+			input:    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0",
+			expected: "Edge, Windows",
+		},
+		{
+			desc:     "Safari on iPhone", // В user agent iPhone есть "Mac OS X"
+			input:    "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			expected: "Safari, iPhone",
+		},
+		{
+			desc:     "Firefox on Linux",
+			input:    "Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0",
+			expected: "Firefox, Linux",
+		},
+		{
+			desc:     "Chrome on Android", // В user agent Android есть "Linux"
+			input:    "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+			expected: "Chrome, Android",
+		},
+		{
+			desc:     "Browser only",
+			input:    "curl/8.5.0",
+			expected: "curl",
+		},
+		{
+			desc:     "Unknown",
+			input:    "",
+			expected: "Неизвестное устройство",
+		},
+	}
+
+	// Action
+	for number, testCase := range testTable {
+		t.Logf("testCase number: %d", number)
+
+		actual := Describe(testCase.input)
+		// Assert
+		requires.Equal(testCase.expected, actual, testCase.desc)
+	}
+}